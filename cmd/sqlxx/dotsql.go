@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"go/token"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/bingoohuang/strcase"
+	flags "github.com/jessevdk/go-flags"
+)
+
+// dotSQLOpts are the options of the dotsql subcommand, which generates the DAO structs from the .sql files,
+// eg. sqlxx dotsql -p dao -o dao/user.go user.sql.
+type dotSQLOpts struct {
+	Pkg  string `short:"p" long:"pkg" default:"dao" description:"package name"`
+	Out  string `short:"o" long:"out" description:"output go file, default stdout"`
+	Args struct {
+		Files []string `positional-arg-name:"file" required:"1" description:".sql files"`
+	} `positional-args:"yes"`
+}
+
+func parseDotSQLArgs(args []string) *dotSQLOpts {
+	var opt dotSQLOpts
+
+	if _, err := flags.ParseArgs(&opt, args); err != nil {
+		if ourErr, ok := err.(*flags.Error); ok && ourErr.Type == flags.ErrHelp {
+			os.Exit(0)
+		}
+
+		os.Exit(1)
+	}
+
+	return &opt
+}
+
+func genDotSQL(opt *dotSQLOpts) {
+	g := &dotSQLGenerator{imports: make(map[string]bool)}
+
+	for _, f := range opt.Args.Files {
+		if err := g.addFile(f); err != nil {
+			fmt.Fprintf(os.Stderr, "generate %s failed error %v\n", f, err)
+
+			os.Exit(1)
+		}
+	}
+
+	source, err := g.gen(sqlx.FixPkgName(opt.Pkg))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "format generated code failed error %v\n", err)
+
+		os.Exit(1)
+	}
+
+	if opt.Out == "" {
+		_, _ = os.Stdout.Write(source)
+		return
+	}
+
+	if err := os.WriteFile(opt.Out, source, 0600); err != nil {
+		fmt.Fprintf(os.Stderr, "write %s failed error %v\n", opt.Out, err)
+
+		os.Exit(1)
+	}
+}
+
+// nolint:gochecknoglobals
+var (
+	typePkgRe = regexp.MustCompile(`\b([a-z]\w*)\.`)
+	// typePkgs are the import paths of the packages used in the declared types.
+	typePkgs = map[string]string{
+		"time":    "time",
+		"sql":     "database/sql",
+		"json":    "encoding/json",
+		"context": "context",
+		"sqlx":    "github.com/bingoohuang/sqlx",
+	}
+)
+
+// dotSQLGenerator generates the DAO structs by the -- param: and -- result: declarations of the .sql files.
+type dotSQLGenerator struct {
+	b       bytes.Buffer
+	imports map[string]bool
+}
+
+// addFile generates the DAO struct of the .sql file, like UserDAO for user.sql.
+func (g *dotSQLGenerator) addFile(sqlFile string) error {
+	d, err := sqlx.DotSQLLoadFile(sqlFile)
+	if err != nil {
+		return err
+	}
+
+	items := make([]sqlx.DotSQLItem, 0, len(d.Sqls))
+	names := make(map[string]bool)
+
+	for _, item := range d.Sqls {
+		if !names[item.Name] {
+			names[item.Name] = true
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return items[i].Line < items[j].Line })
+
+	base := filepath.Base(sqlFile)
+	structName := strcase.ToCamel(strings.TrimSuffix(base, filepath.Ext(base))) + "DAO"
+
+	w := g.b.WriteString
+	w("// " + structName + " is the DAO of " + base + ".\n")
+	w("type " + structName + " struct {\n")
+
+	for _, item := range items {
+		fn, err := g.funcType(item)
+		if err != nil {
+			return err
+		}
+
+		w("\t" + strcase.ToCamel(item.Name) + " " + fn + " `sqlName:\"" + item.Name + "\"`\n")
+	}
+
+	w("}\n\n")
+
+	return nil
+}
+
+// funcType returns the func type of the item, like func(id int64) ([]User, error).
+func (g *dotSQLGenerator) funcType(item sqlx.DotSQLItem) (string, error) {
+	params := make([]string, len(item.Params))
+
+	for i, p := range item.Params {
+		if p.Type == "" {
+			return "", fmt.Errorf("param %s of %s has no type at %s:%d", p.Name, item.Name, item.File, item.Line) // nolint:goerr113
+		}
+
+		name, err := paramName(p.Name)
+		if err != nil {
+			return "", fmt.Errorf("%w of %s at %s:%d", err, item.Name, item.File, item.Line)
+		}
+
+		g.addImports(p.Type)
+		params[i] = name + " " + p.Type
+	}
+
+	fn := "func(" + strings.Join(params, ", ") + ")"
+
+	if item.Result == "" {
+		return fn + " error", nil
+	}
+
+	g.addImports(item.Result)
+
+	return fn + " (" + item.Result + ", error)", nil
+}
+
+// paramName returns the Go name of the param, the keywords like type are suffixed by _.
+func paramName(name string) (string, error) {
+	if token.IsKeyword(name) {
+		return name + "_", nil
+	}
+
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("param %s is not a valid Go identifier", name) // nolint:goerr113
+	}
+
+	return name, nil
+}
+
+func (g *dotSQLGenerator) addImports(typ string) {
+	for _, subs := range typePkgRe.FindAllStringSubmatch(typ, -1) {
+		if p, ok := typePkgs[subs[1]]; ok {
+			g.imports[p] = true
+		}
+	}
+}
+
+func (g *dotSQLGenerator) gen(pkg string) ([]byte, error) {
+	var b bytes.Buffer
+
+	b.WriteString("// Code generated by sqlxx dotsql. DO NOT EDIT.\n\n")
+	b.WriteString("package " + pkg + "\n\n")
+
+	if len(g.imports) > 0 {
+		importPkgs := make([]string, 0, len(g.imports))
+		for k := range g.imports {
+			importPkgs = append(importPkgs, k)
+		}
+
+		sort.Strings(importPkgs)
+
+		b.WriteString("import (\n")
+
+		for _, p := range importPkgs {
+			b.WriteString("\t\"" + p + "\"\n")
+		}
+
+		b.WriteString(")\n\n")
+	}
+
+	_, _ = g.b.WriteTo(&b)
+
+	return format.Source(b.Bytes())
+}
@@ -0,0 +1,15 @@
+// Code generated by sqlxx dotsql. DO NOT EDIT.
+
+package main
+
+import (
+	"time"
+)
+
+// UserDAO is the DAO of user.sql.
+type UserDAO struct {
+	CreateTable func() error                                          `sqlName:"CreateTable"`
+	AddUser     func(name string, type_ string, born time.Time) error `sqlName:"AddUser"`
+	FindNames   func(type_ string) ([]string, error)                  `sqlName:"FindNames"`
+	CountUsers  func() (int64, error)                                 `sqlName:"CountUsers"`
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDotSQLGolden generates the UserDAO of testdata/user.sql, which is compiled as dotsql_golden_test.go.
+func TestDotSQLGolden(t *testing.T) {
+	that := assert.New(t)
+
+	g := &dotSQLGenerator{imports: make(map[string]bool)}
+	that.Nil(g.addFile("testdata/user.sql"))
+
+	source, err := g.gen("main")
+	that.Nil(err)
+
+	golden, err := os.ReadFile("dotsql_golden_test.go")
+	that.Nil(err)
+	that.Equal(string(golden), string(source))
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	that.Nil(err)
+
+	defer db.Close()
+
+	db.SetMaxOpenConns(1)
+
+	dao := &UserDAO{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db), sqlx.WithSQLFile("testdata/user.sql")))
+
+	that.Nil(dao.CreateTable())
+	that.Nil(dao.AddUser("b", "admin", time.Now()))
+	that.Nil(dao.AddUser("a", "admin", time.Now()))
+	that.Nil(dao.AddUser("c", "guest", time.Now()))
+
+	names, err := dao.FindNames("admin")
+	that.Nil(err)
+	that.Equal([]string{"a", "b"}, names)
+
+	count, err := dao.CountUsers()
+	that.Nil(err)
+	that.Equal(int64(3), count)
+}
+
+func TestDotSQLParamName(t *testing.T) {
+	that := assert.New(t)
+
+	g := &dotSQLGenerator{imports: make(map[string]bool)}
+	_, err := g.funcType(sqlx.DotSQLItem{Name: "Find", File: "user.sql", Line: 3,
+		Params: []sqlx.DotSQLParam{{Name: "user-id", Type: "int64"}}})
+	that.EqualError(err, "param user-id is not a valid Go identifier of Find at user.sql:3")
+}
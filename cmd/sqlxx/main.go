@@ -16,11 +16,13 @@ import (
 	"github.com/bingoohuang/strcase"
 	_ "github.com/go-sql-driver/mysql"
 	flags "github.com/jessevdk/go-flags"
+	_ "github.com/mattn/go-sqlite3"
 	"github.com/sirupsen/logrus"
 )
 
 // nolint:lll
 type opts struct {
+	Driver     string `short:"D" required:"false" long:"driver" default:"mysql" description:"driver, eg. mysql or sqlite3"`
 	DataSource string `short:"d" required:"true" long:"dsn" description:"dsn, eg. root:8BE4@127.0.0.1:9633/test"`
 	Pkg        string `short:"p" required:"false" long:"pkg" description:"package name, default lowercase of database name"`
 	Tags       string `short:"t" required:"false" long:"tags" default:"json" description:"tags, eg. json"`
@@ -43,13 +45,20 @@ type Column struct {
 	Extra      string `name:"EXTRA"`       // eg. auto_increment
 }
 
-// nolint:lll
-type mysqlSchemaDao struct {
+// schemaDao queries the schema by the introspection queries of the dialect.
+type schemaDao struct {
 	Logger sqlx.DaoLogger
 
-	Schema  func() string           `sql:"select database()"`
-	Tables  func(s string) []Table  `sql:"select * from information_schema.TABLES where TABLE_SCHEMA=:1"`
-	Columns func(s string) []Column `sql:"select * from information_schema.COLUMNS where TABLE_SCHEMA=:1 order by TABLE_NAME, ORDINAL_POSITION"`
+	Schema  func() string
+	Tables  func() []Table
+	Columns func() []Column
+}
+
+// schemaDotSQL returns the dotsql of the schemaDao by the dialect.
+func schemaDotSQL(d sqlx.Dialect) string {
+	s := d.SchemaSQL()
+
+	return "-- name: Schema\n" + s.Schema + "\n\n-- name: Tables\n" + s.TableInfos + "\n\n-- name: Columns\n" + s.Columns + "\n"
 }
 
 func parseArgs() *opts {
@@ -69,8 +78,18 @@ func parseArgs() *opts {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "dotsql" {
+		genDotSQL(parseDotSQLArgs(os.Args[2:]))
+		return
+	}
+
 	opt := parseArgs()
-	db := sqlx.NewSQLMore("mysql", sqlx.CompatibleMySQLDs(opt.DataSource)).Open()
+	dsn := opt.DataSource
+	if opt.Driver == "mysql" {
+		dsn = sqlx.CompatibleMySQLDs(dsn)
+	}
+
+	db := sqlx.NewSQLMore(opt.Driver, dsn).Open()
 
 	defer db.Close()
 
@@ -78,8 +97,8 @@ func main() {
 
 	logrus.SetLevel(logrus.DebugLevel)
 
-	dao := &mysqlSchemaDao{Logger: &sqlx.DaoLogrus{}}
-	if err := sqlx.CreateDao(dao); err != nil {
+	dao := &schemaDao{Logger: &sqlx.DaoLogrus{}}
+	if err := sqlx.CreateDao(dao, sqlx.WithSQLStr(schemaDotSQL(sqlx.DialectOfDB(db)))); err != nil {
 		panic(err)
 	}
 
@@ -91,11 +110,11 @@ func main() {
 	}
 
 	tablesMap := make(map[string]Table)
-	for _, t := range dao.Tables(schema) {
+	for _, t := range dao.Tables() {
 		tablesMap[t.Name] = t
 	}
 
-	columns := dao.Columns(schema)
+	columns := dao.Columns()
 
 	pkg := sqlx.FixPkgName(str.EmptyThen(opt.Pkg, strings.ToLower(schema)))
 
@@ -0,0 +1,17 @@
+-- name: CreateTable
+create table user(id integer primary key, name varchar(100), type varchar(20), born datetime);
+
+-- name: AddUser
+-- param: name string
+-- param: type string
+-- param: born time.Time
+insert into user(name, type, born) values(:1, :2, :3);
+
+-- name: FindNames
+-- param: type string
+-- result: []string
+select name from user where type = :1 order by name;
+
+-- name: CountUsers
+-- result: int64
+select count(*) from user;
@@ -1,6 +1,7 @@
 package sqlx
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -8,6 +9,8 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/bingoohuang/sqlparser/sqlparser"
 
@@ -49,7 +52,7 @@ func (f StdDB) GetDB() *sql.DB { return f.db }
 var (
 	// DB is the global sql.DB for convenience.
 	DB *sql.DB
-	// SQLReplacer is the global SQLReplacer.
+	// SQLReplacer is the global SQLReplacer, which is overridden by the dao's WithQueryReplacer option.
 	SQLReplacer QueryReplacer
 )
 
@@ -65,22 +68,20 @@ func (d QueryReplacerFn) ReplacerQuery(query string) (string, error) {
 
 // CreateDao fulfils the dao (should be pointer).
 func CreateDao(dao interface{}, createDaoOpts ...CreateDaoOpter) error {
-	daov := reflect.ValueOf(dao)
-	if daov.Kind() != reflect.Ptr || daov.Elem().Kind() != reflect.Struct {
-		return fmt.Errorf("dao should be pointer to struct") // nolint:goerr113
-	}
-
-	option, err := applyCreateDaoOption(createDaoOpts)
+	v, option, err := createDaoOption(dao, createDaoOpts)
 	if err != nil {
 		return err
 	}
 
-	v := reflect.Indirect(daov)
-	createDBGetter(v, option)
-	createLogger(v, option)
 	createErrorSetter(v, option)
 
 	structValue := MakeStructValue(v)
+	if option.strict {
+		if err := validateDao(structValue, option); err != nil {
+			return err
+		}
+	}
+
 	for i := 0; i < structValue.NumField; i++ {
 		f := structValue.FieldByIndex(i)
 
@@ -88,27 +89,20 @@ func CreateDao(dao interface{}, createDaoOpts ...CreateDaoOpter) error {
 			continue
 		}
 
-		tags, err := ParseTags(string(f.Tag))
+		parsed, err := option.parseField(f)
 		if err != nil {
 			return err
 		}
 
-		sqlStmt, sqlName := option.getSQLStmt(f, tags, 0)
-		if sqlStmt == nil {
-			return fmt.Errorf("failed to find sqlName %s", f.Name) // nolint:goerr113
-		}
-
-		parsed := &SQLParsed{
-			ID:  sqlName,
-			SQL: sqlStmt,
-			opt: option,
-		}
-
-		if err := parsed.fastParseSQL(sqlStmt.Raw()); err != nil {
+		if err := parsed.compileExpr(f); err != nil {
 			return err
 		}
 
+		parsed.warmPlan()
+
 		r := sqlRun{SQLParsed: parsed}
+		r.reload = option.createReload(&r, f)
+
 		if err := r.createFn(f); err != nil {
 			return err
 		}
@@ -117,6 +111,60 @@ func CreateDao(dao interface{}, createDaoOpts ...CreateDaoOpter) error {
 	return nil
 }
 
+// createDaoOption creates the options of the dao (should be pointer).
+func createDaoOption(dao interface{}, createDaoOpts []CreateDaoOpter) (reflect.Value, *CreateDaoOpt, error) {
+	daov := reflect.ValueOf(dao)
+	if daov.Kind() != reflect.Ptr || daov.Elem().Kind() != reflect.Struct {
+		return reflect.Value{}, nil, fmt.Errorf("dao should be pointer to struct") // nolint:goerr113
+	}
+
+	option, err := applyCreateDaoOption(createDaoOpts)
+	if err != nil {
+		return reflect.Value{}, nil, err
+	}
+
+	v := reflect.Indirect(daov)
+	createDBGetter(v, option)
+	createLogger(v, option)
+
+	return v, option, nil
+}
+
+// parseField finds the SQL of the dao func field, and parses it.
+func (option *CreateDaoOpt) parseField(f StructField) (*SQLParsed, error) {
+	tags, err := ParseTags(string(f.Tag))
+	if err != nil {
+		return nil, err
+	}
+
+	sqlStmt, sqlName, err := option.getSQLStmt(f, tags, 0)
+	if sqlStmt == nil {
+		if err != nil {
+			return nil, fmt.Errorf("failed to find sqlName %s error %w", f.Name, err)
+		}
+
+		return nil, fmt.Errorf("failed to find sqlName %s", f.Name) // nolint:goerr113
+	}
+
+	parsed := &SQLParsed{
+		ID:       sqlName,
+		SQL:      sqlStmt,
+		opt:      option,
+		primary:  tags.Get("db") == "primary",
+		shardKey: tags.Get("shardKey"),
+	}
+
+	if err := parsed.prepare(); err != nil {
+		return nil, err
+	}
+
+	if err := parsed.prepareSortTags(f.Type); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
 // MapValueOrDefault returns the value associated to the key,
 // or return defaultValue when value does not exits or it is empty.
 func MapValueOrDefault(m map[string]string, key, defaultValue string) string {
@@ -127,12 +175,21 @@ func MapValueOrDefault(m map[string]string, key, defaultValue string) string {
 	return defaultValue
 }
 
-func (option *CreateDaoOpt) getSQLStmt(field StructField, tags Tags, stack int) (SQLPart, string) {
+func (option *CreateDaoOpt) getSQLStmt(field StructField, tags Tags, stack int) (SQLPart, string, error) {
 	if stack > 10 {
-		return nil, ""
+		return nil, "", nil
+	}
+
+	sqlStmt := field.GetTag("sql")
+	if table := field.GetTag("upsert"); table != "" && sqlStmt == "" {
+		var err error
+		if sqlStmt, err = option.upsertSQL(field, table); err != nil {
+			option.Logger.LogError(err)
+			return nil, field.Name, err
+		}
 	}
 
-	if sqlStmt := field.GetTag("sql"); sqlStmt != "" {
+	if sqlStmt != "" {
 		dsi := DotSQLItem{
 			Name:    field.Name,
 			Content: []string{sqlStmt},
@@ -144,25 +201,79 @@ func (option *CreateDaoOpt) getSQLStmt(field StructField, tags Tags, stack int)
 			option.Logger.LogError(err)
 		}
 
-		return part, field.Name
+		return part, field.Name, err
 	}
 
 	sqlName := field.GetTagOr("sqlName", field.Name)
-	if part, err := option.DotSQL(sqlName); err != nil {
+	part, err := option.dotSQL(sqlName)
+
+	if err != nil {
 		option.Logger.LogError(err)
 	} else if part != nil {
-		return part, sqlName
+		return part, sqlName, nil
 	}
 
 	if sqlName == field.Name {
-		return nil, ""
+		return nil, "", err
 	}
 
 	if field, ok := field.Parent.FieldByName(sqlName); ok {
 		return option.getSQLStmt(field, nil, stack+1)
 	}
 
-	return nil, sqlName
+	return nil, sqlName, err
+}
+
+// upsertSQL creates the upsert statement of the table by the dialect of the dao, the columns are of the
+// struct (or its slice) param after the optional leading context, and the conflict keys are of the keys tag (default id).
+func (option *CreateDaoOpt) upsertSQL(field StructField, table string) (string, error) {
+	t, i := field.Type, 0
+	if t.NumIn() > 0 && t.In(0) == _ctxType {
+		i = 1
+	}
+
+	var bean reflect.Type
+	if t.NumIn() > i {
+		if bean = t.In(i); bean.Kind() == reflect.Slice {
+			bean = bean.Elem()
+		}
+	}
+
+	if bean == nil || bean.Kind() != reflect.Struct {
+		return "", fmt.Errorf("upsert %s requires the struct param", field.Name) // nolint:goerr113
+	}
+
+	columns := make([]string, 0, bean.NumField())
+
+	for _, f := range structTypeOf(bean).fields {
+		if f.PkgPath != "" {
+			continue
+		}
+
+		if tagName := f.Tag.Get("name"); tagName != "" {
+			columns = append(columns, tagName)
+		} else {
+			columns = append(columns, strcase.ToSnake(f.Name))
+		}
+	}
+
+	keys := strings.Split(field.GetTagOr("keys", "id"), ",")
+	for j, k := range keys {
+		keys[j] = strings.TrimSpace(k)
+	}
+
+	return DialectOf(option.getDBType()).Upsert(table, columns, keys), nil
+}
+
+// dotSQL looks up the SQL by name, the variant of the dbtype is preferred when the dbtype is known.
+func (option *CreateDaoOpt) dotSQL(sqlName string) (SQLPart, error) {
+	if dbType := option.getDBType(); dbType != "" && option.variant != nil {
+		if part, ok, err := option.variant(sqlName, dbType); ok {
+			return part, err
+		}
+	}
+
+	return option.DotSQL(sqlName)
 }
 
 func (r *sqlRun) createFn(f StructField) error {
@@ -210,21 +321,25 @@ func (r *sqlRun) createFn(f StructField) error {
 }
 
 func (r *sqlRun) MakeFunc(f StructField, numIn, numOut int) func([]reflect.Value) ([]reflect.Value, error) {
-	var fn func(int, StructField, []reflect.Type, []reflect.Value) ([]reflect.Value, error)
-
-	switch isBindByName := r.isBindBy(ByName); {
-	case !r.IsQuery && isBindByName:
-		fn = r.execByName
-	case !r.IsQuery && !isBindByName:
-		fn = r.execBySeq
-	case r.IsQuery && isBindByName:
-		fn = r.queryByName
-	default: // isQuery && !isBindByName:
-		fn = r.queryBySeq
+	// the leading context.Context argument is used as the context of the call.
+	withCtx := numIn > 0 && f.Type.In(0) == _ctxType
+	if withCtx {
+		numIn--
 	}
 
 	return func(args []reflect.Value) ([]reflect.Value, error) {
-		return fn(numIn, f, makeOutTypes(f.Type, numOut), args)
+		run := r.current()
+
+		if withCtx {
+			parsed := *run.SQLParsed
+			if !args[0].IsNil() {
+				parsed.ctx = args[0].Interface().(context.Context)
+			}
+
+			run, args = &sqlRun{SQLParsed: &parsed}, args[1:]
+		}
+
+		return run.runFn()(run, numIn, f, makeOutTypes(f.Type, numOut), args)
 	}
 }
 
@@ -240,23 +355,156 @@ func makeOutTypes(outType reflect.Type, numOut int) []reflect.Type {
 
 type sqlRun struct {
 	*SQLParsed
+
+	reload *sqlReload
+}
+
+// prepare parses the SQL for the bind mode and the sort whitelist.
+func (p *SQLParsed) prepare() error {
+	if err := p.fastParseSQL(p.SQL.Raw()); err != nil {
+		return err
+	}
+
+	p.plans = newSQLPlans(p.SQL)
+
+	var err error
+	if p.sortable, err = sortableOf(p.SQL); err != nil {
+		return fmt.Errorf("failed to parse sort whitelist of %s error %w", p.ID, err)
+	}
+
+	return nil
+}
+
+// sqlReload reparses the SQL of the dao func when the watched dotsql is reloaded,
+// or when the dbtype of the dao is detected after CreateDao, like the db assigned later.
+type sqlReload struct {
+	sync.Mutex
+	watcher *DotSQLWatcher
+	version uint64
+	dbType  string
+	// resolved tells the dbtype is detected and no dotsql is watched, so the run is final.
+	resolved int32
+	run      *sqlRun
+	f        StructField
+}
+
+// createReload creates the sqlReload of the dao func, nil when the SQL is never reparsed.
+func (option *CreateDaoOpt) createReload(r *sqlRun, f StructField) *sqlReload {
+	dbType, known := option.lookupDBType()
+	s := &sqlReload{dbType: dbType, run: r, f: f}
+
+	if option.watcher != nil && option.watcher.has(r.ID) {
+		s.watcher, s.version = option.watcher, option.watcher.Version()
+	} else if known {
+		return nil
+	}
+
+	return s
+}
+
+// current returns the sqlRun to be called, which is reparsed when the watched dotsql is reloaded
+// or the dbtype is detected.
+func (r *sqlRun) current() *sqlRun {
+	if r.reload == nil {
+		return r
+	}
+
+	s := r.reload
+	if atomic.LoadInt32(&s.resolved) == 1 {
+		return s.run
+	}
+
+	var v uint64
+	if s.watcher != nil {
+		v = s.watcher.Version()
+	}
+
+	dbType, known := r.opt.lookupDBType()
+
+	s.Lock()
+	defer s.Unlock()
+
+	if known && s.watcher == nil {
+		defer atomic.StoreInt32(&s.resolved, 1)
+	}
+
+	if v == s.version && dbType == s.dbType {
+		return s.run
+	}
+
+	s.version, s.dbType = v, dbType
+	old := s.run.SQLParsed
+
+	parsed, err := s.reparse(old)
+	if err != nil {
+		old.logError(fmt.Errorf("reload %s error %w, the old version is kept", old.ID, err))
+		return s.run
+	}
+
+	s.run = &sqlRun{SQLParsed: parsed}
+
+	return s.run
+}
+
+// reparse parses the SQL of the dao func again by the reloaded dotsql and the detected dbtype.
+func (s *sqlReload) reparse(old *SQLParsed) (*SQLParsed, error) {
+	parsed, err := old.opt.parseField(s.f)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := parsed.compileExpr(s.f); err != nil {
+		return nil, err
+	}
+
+	parsed.warmPlan()
+
+	return parsed, nil
+}
+
+// runFn returns the func to run the SQL by its bind mode and whether it is a query.
+func (r *sqlRun) runFn() func(*sqlRun, int, StructField, []reflect.Type, []reflect.Value) ([]reflect.Value, error) {
+	switch isBindByName := r.isBindBy(ByName); {
+	case !r.IsQuery && isBindByName:
+		return (*sqlRun).execByName
+	case !r.IsQuery && !isBindByName:
+		return (*sqlRun).execBySeq
+	case r.IsQuery && isBindByName:
+		return (*sqlRun).queryByName
+	default: // isQuery && !isBindByName:
+		return (*sqlRun).queryBySeq
+	}
 }
 
 func (p *SQLParsed) evalSeq(numIn int, f StructField, args []reflect.Value) error {
 	env := make(map[string]interface{})
-	for i, arg := range args {
-		env[fmt.Sprintf("_%d", i+1)] = arg.Interface()
+	if !p.isStatic() {
+		for i, arg := range args {
+			env[fmt.Sprintf("_%d", i+1)] = arg.Interface()
+		}
 	}
 
 	if len(args) > 0 {
-		env = p.createFieldSqlParts(env, args[0])
+		var err error
+		if env, err = p.createFieldSqlParts(env, args[0]); err != nil {
+			return err
+		}
+	}
+
+	if err := p.createSort(args); err != nil {
+		return err
 	}
 
 	return p.eval(numIn, f, env)
 }
 
 func (p *SQLParsed) eval(numIn int, f StructField, env map[string]interface{}) error {
-	runSQL, err := p.SQL.Eval(env)
+	p.evalVars = evalVarsOf(env)
+	if p.opt != nil {
+		env[dbTypeKey] = p.opt.getDBType()
+	}
+
+	runSQL, err := p.evalSQL(env)
 	if err != nil {
 		return err
 	}
@@ -265,7 +513,7 @@ func (p *SQLParsed) eval(numIn int, f StructField, env map[string]interface{}) e
 		return err
 	}
 
-	if err := p.checkFuncInOut(numIn, f); err != nil {
+	if err := p.checkFuncInOutOnce(numIn, f); err != nil {
 		return err
 	}
 
@@ -281,25 +529,25 @@ func (r *sqlRun) queryByName(numIn int, f StructField,
 	}
 
 	parsed := *r.SQLParsed
-	env := parsed.createNamedMap(bean)
+	env, err := parsed.createFieldSqlParts(parsed.namedEnv(bean), bean)
+	if err != nil {
+		return nil, err
+	}
 
-	if err := parsed.eval(numIn, f, env); err != nil {
+	if err := parsed.createSort([]reflect.Value{bean}); err != nil {
 		return nil, err
 	}
 
-	vars, err := parsed.createNamedVars(bean)
-	if err != nil {
+	if err := parsed.eval(numIn, f, env); err != nil {
 		return nil, err
 	}
 
-	counterIndex := indexOfTypes(outTypes, CountType)
-	db := r.opt.DBGetter.GetDB()
-	rows, counter, err := parsed.doQueryDirectVars(db, vars, counterIndex >= 0)
+	vars, err := parsed.createNamedVars(bean)
 	if err != nil {
 		return nil, err
 	}
 
-	return parsed.wrapCounter(rows, outTypes, counterIndex, counter)
+	return parsed.routeQuery([]reflect.Value{bean}, vars, outTypes)
 }
 
 func (p *SQLParsed) wrapCounter(rows *sql.Rows, outTypes []reflect.Type, counterIndex int, counterFn func() (int64, error)) ([]reflect.Value, error) {
@@ -327,7 +575,8 @@ func indexOfTypes(types []reflect.Type, typ reflect.Type) int {
 	return -1
 }
 
-// nolint:funlen
+// execByName executes the statement by the named bean, or each of the beans of the slice.
+// The beans are routed to their shards one by one, and executed in a transaction per shard.
 func (r *sqlRun) execByName(numIn int, f StructField, outTypes []reflect.Type,
 	args []reflect.Value) ([]reflect.Value, error) {
 	var bean reflect.Value
@@ -336,83 +585,111 @@ func (r *sqlRun) execByName(numIn int, f StructField, outTypes []reflect.Type,
 		bean = args[0]
 	}
 
-	item0 := bean
-	itemSize := 1
-	isBeanSlice := bean.IsValid() && bean.Type().Kind() == reflect.Slice
+	items := []reflect.Value{bean}
 
-	if isBeanSlice {
+	if bean.IsValid() && bean.Type().Kind() == reflect.Slice {
 		if bean.IsNil() || bean.Len() == 0 {
 			return []reflect.Value{}, nil
 		}
 
-		item0 = bean.Index(0)
-		itemSize = bean.Len()
+		items = make([]reflect.Value, bean.Len())
+		for i := range items {
+			items[i] = bean.Index(i)
+		}
+	}
+
+	parsed := *r.SQLParsed
+
+	shards, err := parsed.routeExecItems(items)
+	if err != nil {
+		return nil, err
 	}
 
 	var (
-		err        error
-		pr         *sql.Stmt
 		lastResult sql.Result
 		lastSQL    string
 	)
 
-	parsed := *r.SQLParsed
-	db := r.opt.DBGetter.GetDB()
-	tx, err := db.BeginTx(parsed.opt.Ctx, nil)
+	for _, shard := range shards {
+		parsed.shardReplacer = shard.Replacer
+
+		if lastResult, lastSQL, err = parsed.execItems(numIn, f, shard.DB, shard.items); err != nil {
+			return nil, err
+		}
+	}
+
+	return convertExecResult(lastResult, lastSQL, outTypes)
+}
+
+// execItems executes the statement by each of the named items in a transaction of the db,
+// and returns the result and the SQL of the last item.
+func (p *SQLParsed) execItems(numIn int, f StructField, db *sql.DB,
+	items []reflect.Value) (lastResult sql.Result, lastSQL string, err error) {
+	tx, err := db.BeginTx(p.getCtx(), nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to begin tx %w", err)
+		return nil, "", fmt.Errorf("failed to begin tx %w", classifyError(db, err))
 	}
 
-	for ii := 0; ii < itemSize; ii++ {
-		if ii > 0 {
-			item0 = bean.Index(ii)
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
 		}
+	}()
 
-		namedMap := parsed.createNamedMap(item0)
-		if err := parsed.eval(numIn, f, namedMap); err != nil {
-			return nil, err
+	var pr *sql.Stmt
+
+	for _, item := range items {
+		if err := p.eval(numIn, f, p.namedEnv(item)); err != nil {
+			return nil, "", err
 		}
 
-		if lastSQL != parsed.runSQL {
-			lastSQL = parsed.runSQL
+		if lastSQL != p.runSQL {
+			lastSQL = p.runSQL
 
-			query, err := r.replaceQuery(parsed.runSQL)
+			query, err := p.replaceQuery(p.runSQL)
 			if err != nil {
-				return nil, fmt.Errorf("replaceQuery %s error %w", parsed.runSQL, err)
+				return nil, "", fmt.Errorf("replaceQuery %s error %w", p.runSQL, err)
 			}
-			if pr, err = tx.PrepareContext(parsed.opt.Ctx, query); err != nil {
-				return nil, fmt.Errorf("failed to prepare sql %s error %w", r.RawStmt, err)
+
+			if pr, err = tx.PrepareContext(p.getCtx(), query); err != nil {
+				return nil, "", p.errorAt(fmt.Errorf("failed to prepare sql %s error %w", p.RawStmt, classifyError(db, err)))
 			}
 		}
 
-		vars, err := parsed.createNamedVars(item0)
+		vars, err := p.createNamedVars(item)
 		if err != nil {
-			return nil, err
+			return nil, "", err
 		}
 
-		parsed.logPrepare(vars)
+		p.logPrepare(vars)
 
-		lastResult, err = pr.ExecContext(parsed.opt.Ctx, vars...)
-
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute %s with vars %v error %w", parsed.runSQL, vars, err)
+		if lastResult, err = pr.ExecContext(p.getCtx(), vars...); err != nil {
+			return nil, "", p.errorAt(fmt.Errorf("failed to execute %s with vars %v error %w", p.runSQL, vars, classifyError(db, err)))
 		}
 	}
 
 	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commiterror %w", err)
+		return nil, "", fmt.Errorf("failed to commiterror %w", classifyError(db, err))
 	}
 
-	return convertExecResult(lastResult, lastSQL, outTypes)
+	return lastResult, lastSQL, nil
 }
 
-func (p *SQLParsed) createFieldSqlParts(m map[string]interface{}, bean reflect.Value) map[string]interface{} {
+func (p *SQLParsed) createFieldSqlParts(m map[string]interface{},
+	bean reflect.Value) (map[string]interface{}, error) {
 	if !bean.IsValid() || bean.Type().Kind() != reflect.Struct {
-		return m
+		return m, nil
 	}
 
-	structValue := MakeStructValue(bean)
-	for i, f := range structValue.FieldTypes {
+	for i, f := range structTypeOf(bean.Type()).fields {
+		if col := f.Tag.Get("col"); col != "" {
+			if err := p.createQBEPart(f, col, bean.Field(i)); err != nil {
+				return nil, err
+			}
+
+			continue
+		}
+
 		if sqlPart := f.Tag.Get("sql"); sqlPart != "" {
 			if bean.Field(i).IsZero() {
 				continue
@@ -429,7 +706,16 @@ func (p *SQLParsed) createFieldSqlParts(m map[string]interface{}, bean reflect.V
 		}
 	}
 
-	return m
+	return m, nil
+}
+
+// namedEnv returns the env of the named bean to evaluate the SQL, which is empty for the static SQL.
+func (p *SQLParsed) namedEnv(bean reflect.Value) map[string]interface{} {
+	if p.isStatic() {
+		return make(map[string]interface{})
+	}
+
+	return p.createNamedMap(bean)
 }
 
 func (p *SQLParsed) createNamedMap(bean reflect.Value) map[string]interface{} {
@@ -440,15 +726,8 @@ func (p *SQLParsed) createNamedMap(bean reflect.Value) map[string]interface{} {
 
 	switch bean.Type().Kind() {
 	case reflect.Struct:
-		structValue := MakeStructValue(bean)
-		for i, f := range structValue.FieldTypes {
-			if tagName := f.Tag.Get("name"); tagName != "" {
-				m[tagName] = bean.Field(i).Interface()
-			} else {
-				name := strcase.ToCamelLower(f.Name)
-				m[name] = bean.Field(i).Interface()
-			}
-
+		for i, name := range structTypeOf(bean.Type()).names {
+			m[name] = bean.Field(i).Interface()
 		}
 	case reflect.Map:
 		for _, k := range bean.MapKeys() {
@@ -464,18 +743,20 @@ func (p *SQLParsed) createNamedMap(bean reflect.Value) map[string]interface{} {
 func (p *SQLParsed) createNamedVars(bean reflect.Value) ([]interface{}, error) {
 	itemType := bean.Type()
 
-	var namedValueParser func(name string, item reflect.Value, itemType reflect.Type) interface{}
+	var namedValueParser func(name string, item reflect.Value, itemType reflect.Type) reflect.Value
 
 	switch itemType.Kind() {
 	case reflect.Struct:
-		namedValueParser = func(name string, item reflect.Value, itemType reflect.Type) interface{} {
-			return item.FieldByNameFunc(func(f string) bool {
-				return matchesField2Col(itemType, f, name)
-			}).Interface()
+		namedValueParser = func(name string, item reflect.Value, itemType reflect.Type) reflect.Value {
+			if c := columnFieldOf(itemType, name); c.ok {
+				return c.value(item)
+			}
+
+			return reflect.Value{}
 		}
 	case reflect.Map:
-		namedValueParser = func(name string, item reflect.Value, itemType reflect.Type) interface{} {
-			return item.MapIndex(reflect.ValueOf(name)).Interface()
+		namedValueParser = func(name string, item reflect.Value, itemType reflect.Type) reflect.Value {
+			return item.MapIndex(reflect.ValueOf(name))
 		}
 	}
 
@@ -484,13 +765,51 @@ func (p *SQLParsed) createNamedVars(bean reflect.Value) ([]interface{}, error) {
 		return nil, fmt.Errorf("named vars should use struct/map, unsupported type %v", itemType)
 	}
 
-	vars := make([]interface{}, len(p.Vars))
+	// the vars of the query-by-example field parts are appended after the named ones.
+	vars := make([]interface{}, len(p.Vars)-len(p.fp.fieldVars), len(p.Vars))
+
+	for i, name := range p.Vars[:len(vars)] {
+		if v, ok := p.evalVars[name]; ok {
+			vars[i] = v
+			continue
+		}
+
+		v := namedValueParser(name, bean, itemType)
+
+		// the default like :limit|100 is bound when the named value is absent or nil.
+		if def, ok := p.defaults[name]; ok && (!v.IsValid() || isNilValue(v)) {
+			vars[i] = def
+		} else if !v.IsValid() {
+			return nil, fmt.Errorf("named param :%s is not found in %v", name, itemType) // nolint:goerr113
+		} else {
+			vars[i] = v.Interface()
+		}
+	}
+
+	if p.named {
+		return namedArgs(p.Vars, vars), nil
+	}
+
+	return p.reorderVars(append(vars, p.fp.fieldVars...)), nil
+}
+
+// isNilValue tells whether the value is a nil pointer, interface, map or slice.
+func isNilValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice:
+		return v.IsNil()
+	default:
+		return false
+	}
+}
 
-	for i, name := range p.Vars {
-		vars[i] = namedValueParser(name, bean, itemType)
+// errorAt wraps the error by the position of the dotsql statement, like users.sql:42.
+func (p *SQLParsed) errorAt(err error) error {
+	if pp, ok := p.SQL.(*PostProcessingSQLPart); ok {
+		return pp.src.errorAt(err)
 	}
 
-	return vars, nil
+	return err
 }
 
 func (p *SQLParsed) logPrepare(vars interface{}) {
@@ -508,15 +827,21 @@ func (r *sqlRun) execBySeq(numIn int, f StructField,
 	vars := parsed.makeVars(args)
 	parsed.logPrepare(vars)
 
-	db := r.opt.DBGetter.GetDB()
-	query, err := r.replaceQuery(parsed.runSQL)
+	shard, err := parsed.routeExecDB(args)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed.shardReplacer = shard.Replacer
+
+	query, err := parsed.replaceQuery(parsed.runSQL)
 	if err != nil {
 		return nil, fmt.Errorf("replaceQuery %s error %w", parsed.runSQL, err)
 	}
 
-	result, err := db.ExecContext(parsed.opt.Ctx, query, vars...)
+	result, err := shard.DB.ExecContext(parsed.getCtx(), query, vars...)
 	if err != nil {
-		return nil, fmt.Errorf("execute %s error %w", r.SQL, err)
+		return nil, parsed.errorAt(fmt.Errorf("execute %s error %w", r.SQL, classifyError(shard.DB, err)))
 	}
 
 	results, err := convertExecResult(result, query, outTypes)
@@ -535,17 +860,7 @@ func (r *sqlRun) queryBySeq(numIn int, f StructField,
 		return nil, err
 	}
 
-	db := r.opt.DBGetter.GetDB()
-	counterIndex := indexOfTypes(outTypes, CountType)
-
-	rows, counterFn, err := parsed.doQuery(db, args, counterIndex >= 0)
-	if err != nil {
-		return nil, err
-	}
-
-	defer rows.Close()
-
-	return parsed.wrapCounter(rows, outTypes, counterIndex, counterFn)
+	return parsed.routeQuery(args, parsed.makeVars(args), outTypes)
 }
 
 func (p *SQLParsed) processQueryRows(rows *sql.Rows, outTypes []reflect.Type) ([]reflect.Value, error) {
@@ -573,8 +888,10 @@ func (p *SQLParsed) processQueryRows(rows *sql.Rows, outTypes []reflect.Type) ([
 		return nil, err
 	}
 
+	pointers := make([]interface{}, len(mapFields))
+
 	for ri := 0; rows.Next() && (p.opt.QueryMaxRows <= 0 || ri < p.opt.QueryMaxRows); ri++ {
-		pointers, out := resetDests(out0Type, out0TypePtr, outTypes, mapFields)
+		out := resetDests(out0Type, out0TypePtr, outTypes, mapFields, pointers)
 		if err := rows.Scan(pointers[:len(columns)]...); err != nil {
 			return nil, fmt.Errorf("scan rows %s error %w", p.SQL, err)
 		}
@@ -637,11 +954,6 @@ func (p *SQLParsed) getRowScanInterceptorFn() RowScanInterceptorFn {
 	return nil
 }
 
-func (p *SQLParsed) doQuery(db *sql.DB, args []reflect.Value, counting bool) (*sql.Rows, func() (int64, error), error) {
-	vars := p.makeVars(args)
-	return p.doQueryDirectVars(db, vars, counting)
-}
-
 func (p *SQLParsed) doQueryDirectVars(db *sql.DB, vars []interface{}, counting bool) (*sql.Rows, func() (int64, error), error) {
 	p.logPrepare(vars)
 
@@ -650,18 +962,18 @@ func (p *SQLParsed) doQueryDirectVars(db *sql.DB, vars []interface{}, counting b
 		return nil, nil, fmt.Errorf("replaceQuery %s error %w", query, err)
 	}
 
-	rows, err := db.QueryContext(p.opt.Ctx, query, vars...)
+	rows, err := db.QueryContext(p.getCtx(), query, vars...)
 	if err != nil || rows.Err() != nil {
 		if err == nil {
 			err = rows.Err()
 		}
 
-		return nil, nil, fmt.Errorf("execute %s error %w", query, err)
+		return nil, nil, p.errorAt(fmt.Errorf("execute %s error %w", query, classifyError(db, err)))
 	}
 
 	if counting {
 		return rows, func() (int64, error) {
-			count, err := p.pagingCount(db, query, vars)
+			count, err := p.pagingCount(db, p.runSQL, vars)
 			return count, err
 		}, nil
 	}
@@ -669,6 +981,11 @@ func (p *SQLParsed) doQueryDirectVars(db *sql.DB, vars []interface{}, counting b
 	return rows, nil, nil
 }
 
+func isValArg(expr sqlparser.Expr) bool {
+	v, ok := expr.(*sqlparser.SQLVal)
+	return ok && v.Type == sqlparser.ValArg
+}
+
 func (p *SQLParsed) pagingCount(db *sql.DB, query string, vars []interface{}) (int64, error) {
 	parsed, err := sqlparser.Parse(query)
 	if err != nil {
@@ -688,8 +1005,11 @@ func (p *SQLParsed) pagingCount(db *sql.DB, query string, vars []interface{}) (i
 
 	limitVarsCount := 0
 	if oldLimit != nil {
-		limitVarsCount++
-		if oldLimit.Offset != nil {
+		if isValArg(oldLimit.Rowcount) {
+			limitVarsCount++
+		}
+
+		if isValArg(oldLimit.Offset) {
 			limitVarsCount++
 		}
 	}
@@ -704,13 +1024,13 @@ func (p *SQLParsed) pagingCount(db *sql.DB, query string, vars []interface{}) (i
 		return 0, fmt.Errorf("replaceQuery %s error %w", countQuery, err)
 	}
 
-	rows, err := db.QueryContext(p.opt.Ctx, countQuery, vars...)
+	rows, err := db.QueryContext(p.getCtx(), countQuery, vars...)
 	if err != nil || rows.Err() != nil {
 		if err == nil {
 			err = rows.Err()
 		}
 
-		return 0, fmt.Errorf("execute %s error %w", countQuery, err)
+		return 0, fmt.Errorf("execute %s error %w", countQuery, classifyError(db, err))
 	}
 
 	defer rows.Close()
@@ -785,12 +1105,8 @@ func (p *SQLParsed) makeMapField(col string, outType reflect.Type) selectItem {
 }
 
 func (p *SQLParsed) makeStructField(col string, outType reflect.Type) selectItem {
-	fv, ok := outType.FieldByNameFunc(func(field string) bool {
-		return matchesField2Col(outType, field, col)
-	})
-
-	if ok {
-		return &structItem{StructField: &fv}
+	if c := columnFieldOf(outType, col); c.ok {
+		return &structItem{StructField: &c.field, set: c.set}
 	}
 
 	return nil
@@ -799,9 +1115,14 @@ func (p *SQLParsed) makeStructField(col string, outType reflect.Type) selectItem
 func (p *SQLParsed) makeVars(args []reflect.Value) []interface{} {
 	vars := make([]interface{}, 0, len(p.Vars))
 
-	for i, name := range p.Vars[:len(p.Vars)-len(p.fp.fieldVars)] {
-		if p.BindBy == ByAuto {
-			vars = append(vars, args[i].Interface())
+	auto := 0
+
+	for _, name := range p.Vars[:len(p.Vars)-len(p.fp.fieldVars)] {
+		if v, ok := p.evalVars[name]; ok {
+			vars = append(vars, v)
+		} else if p.BindBy == ByAuto {
+			vars = append(vars, args[auto].Interface())
+			auto++
 		} else {
 			seq, _ := strconv.Atoi(name)
 			vars = append(vars, args[seq-1].Interface())
@@ -812,7 +1133,7 @@ func (p *SQLParsed) makeVars(args []reflect.Value) []interface{} {
 		vars = append(vars, p.fp.fieldVars...)
 	}
 
-	return vars
+	return p.reorderVars(vars)
 }
 
 func (p *SQLParsed) logError(err error) {
@@ -858,14 +1179,12 @@ type selectItem interface {
 type structItem struct {
 	*reflect.StructField
 	parent reflect.Value
+	set    func(structValue, val reflect.Value)
 }
 
 func (s *structItem) Type() reflect.Type               { return s.StructField.Type }
 func (s *structItem) ResetParent(parent reflect.Value) { s.parent = parent }
-func (s *structItem) Set(val reflect.Value) {
-	f := s.parent.FieldByName(s.StructField.Name)
-	f.Set(val.Convert(f.Type()))
-}
+func (s *structItem) Set(val reflect.Value)            { s.set(s.parent, val) }
 
 type mapItem struct {
 	k      reflect.Value
@@ -893,10 +1212,10 @@ func (s *singleValue) Set(val reflect.Value) {
 	s.parent.Set(val)
 }
 
+// resetDests resets the scan destinations of the pointers for the next row, and returns the outputs of the row.
+// The NullAny pointers are reused across the rows, the sql.Scanner ones are created for each row.
 func resetDests(out0Type reflect.Type, out0TypePtr bool,
-	outTypes []reflect.Type, mapFields []selectItem) ([]interface{}, []reflect.Value) {
-	pointers := make([]interface{}, len(mapFields))
-
+	outTypes []reflect.Type, mapFields []selectItem, pointers []interface{}) []reflect.Value {
 	var out0 reflect.Value
 
 	out := make([]reflect.Value, len(outTypes))
@@ -925,7 +1244,10 @@ func resetDests(out0Type reflect.Type, out0TypePtr bool,
 
 	for i, fv := range mapFields {
 		if fv == nil {
-			pointers[i] = &NullAny{Type: nil}
+			if pointers[i] == nil {
+				pointers[i] = &NullAny{Type: nil}
+			}
+
 			continue
 		}
 
@@ -938,14 +1260,16 @@ func resetDests(out0Type reflect.Type, out0TypePtr bool,
 			fv.ResetParent(out[i])
 		}
 
-		if ImplSQLScanner(fv.Type()) {
+		if n, ok := pointers[i].(*NullAny); ok {
+			n.Val = reflect.Value{}
+		} else if implSQLScanner(fv.Type()) {
 			pointers[i] = reflect.New(fv.Type()).Interface()
 		} else {
 			pointers[i] = &NullAny{Type: fv.Type()}
 		}
 	}
 
-	return pointers, out
+	return out
 }
 
 func fillFields(mapFields []selectItem, pointers []interface{}) {
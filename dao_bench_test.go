@@ -0,0 +1,131 @@
+package sqlx_test
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/sqlx"
+)
+
+type benchRow struct {
+	ID      int64
+	Name    string
+	Age     int
+	Email   string `name:"email_addr"`
+	Score   float64
+	Created time.Time
+}
+
+type benchDao struct {
+	CreateTable func()            `sql:"create table bench_row(id int, name varchar(100), age int, email_addr varchar(100), score float, created datetime)"`
+	AddAll      func(...benchRow) `sql:"insert into bench_row(id, name, age, email_addr, score, created) values(:id, :name, :age, :email_addr, :score, :created)"`
+	ListAll     func() []benchRow `sql:"select id, name, age, email_addr, score, created from bench_row"`
+}
+
+const benchRows = 10000
+
+func makeBenchRows() []benchRow {
+	rows := make([]benchRow, benchRows)
+	now := time.Now()
+
+	for i := range rows {
+		s := strconv.Itoa(i)
+		rows[i] = benchRow{ID: int64(i), Name: "name" + s, Age: i % 100, Email: s + "@b.c", Score: float64(i), Created: now}
+	}
+
+	return rows
+}
+
+func createBenchDao(b *testing.B) *benchDao {
+	db := openSingleDB(&testing.T{})
+	dao := &benchDao{}
+
+	if err := sqlx.CreateDao(dao, sqlx.WithDB(db)); err != nil {
+		b.Fatal(err)
+	}
+
+	dao.CreateTable()
+
+	return dao
+}
+
+func BenchmarkDaoScan10kRows(b *testing.B) {
+	dao := createBenchDao(b)
+	dao.AddAll(makeBenchRows()...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if rows := dao.ListAll(); len(rows) != benchRows {
+			b.Fatalf("expected %d rows, got %d", benchRows, len(rows))
+		}
+	}
+}
+
+func BenchmarkDaoNamedVars10kRows(b *testing.B) {
+	dao := createBenchDao(b)
+	rows := makeBenchRows()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		dao.AddAll(rows...)
+	}
+}
+
+type benchCallDao struct {
+	CreateTable func()                             `sql:"create table bench_row(id int, name varchar(100), age int, email_addr varchar(100), score float, created datetime)"`
+	AddAll      func(...benchRow)                  `sql:"insert into bench_row(id, name, age, email_addr, score, created) values(:id, :name, :age, :email_addr, :score, :created)"`
+	Find        func(id int64) benchRow            `sql:"select id, name, age, email_addr, score, created from bench_row where id = :1"`
+	FindByName  func(benchRow) benchRow            `sql:"select id, name, age, email_addr, score, created from bench_row where id = :id"`
+	FindDynamic func(id int64, age int) []benchRow `sql:"select id, name, age, email_addr, score, created from bench_row where id = :1 /* if _2 > 0 */ and age = :2 /* end */"`
+}
+
+func createBenchCallDao(b *testing.B) *benchCallDao {
+	dao := &benchCallDao{}
+
+	if err := sqlx.CreateDao(dao, sqlx.WithDB(openSingleDB(&testing.T{}))); err != nil {
+		b.Fatal(err)
+	}
+
+	dao.CreateTable()
+	dao.AddAll(makeBenchRows()[:10]...)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	return dao
+}
+
+func BenchmarkDaoStaticCall(b *testing.B) {
+	dao := createBenchCallDao(b)
+
+	for i := 0; i < b.N; i++ {
+		if row := dao.Find(3); row.ID != 3 {
+			b.Fatalf("expected row 3, got %d", row.ID)
+		}
+	}
+}
+
+func BenchmarkDaoStaticNamedCall(b *testing.B) {
+	dao := createBenchCallDao(b)
+
+	for i := 0; i < b.N; i++ {
+		if row := dao.FindByName(benchRow{ID: 3}); row.ID != 3 {
+			b.Fatalf("expected row 3, got %d", row.ID)
+		}
+	}
+}
+
+func BenchmarkDaoDynamicCall(b *testing.B) {
+	dao := createBenchCallDao(b)
+
+	for i := 0; i < b.N; i++ {
+		if rows := dao.FindDynamic(3, i%2*3); len(rows) != 1 {
+			b.Fatalf("expected 1 row, got %d", len(rows))
+		}
+	}
+}
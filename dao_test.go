@@ -4,7 +4,12 @@ import (
 	"context"
 	"database/sql"
 	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -577,3 +582,732 @@ func TestTime(t *testing.T) {
 	effectedRows := dao.Delete(lastInsertID)
 	that.Equal(1, effectedRows)
 }
+
+type sortCond struct {
+	Addr string    `sql:"addr like ?"`
+	Sort sqlx.Sort `sort:"addr"`
+}
+
+type personSortDao struct {
+	CreateTable func()                      `sql:"create table person(id varchar(100), age int, addr varchar(10))"`
+	Add         func(personMap)             `sql:"insert into person(id, age, addr) values(:id, :age, :addr)"`
+	List        func(sqlx.Sort) []personMap `sql:"select id, age, addr from person order by id" sort:"id,age=age"`
+	ListCond    func(sortCond) []personMap  `sql:"select id, age, addr from person" sort:"age"`
+	ListByName  func(M) []personMap         `sqlName:"ListByAge"`
+
+	Error error
+}
+
+const dotSQLSort = `
+-- name: ListByAge sort: id,age=age,addr=lower(addr)
+select id, age, addr from person where age > :age order by id;
+`
+
+func TestSort(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personSortDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLSort)))
+
+	dao.CreateTable()
+	dao.Add(personMap{ID: "1", Age: 30, Addr: "b"})
+	dao.Add(personMap{ID: "2", Age: 10, Addr: "c"})
+	dao.Add(personMap{ID: "3", Age: 20, Addr: "a"})
+
+	that.Equal([]personMap{{"1", 30, "b"}, {"2", 10, "c"}, {"3", 20, "a"}}, dao.List(nil))
+
+	sort, err := sqlx.ParseSort("-age")
+	that.Nil(err)
+	that.Equal([]personMap{{"1", 30, "b"}, {"3", 20, "a"}, {"2", 10, "c"}}, dao.List(sort))
+	that.Equal([]personMap{{"2", 10, "c"}, {"3", 20, "a"}, {"1", 30, "b"}}, dao.List(sqlx.Sort{{Field: "age"}}))
+
+	that.Nil(dao.List(sqlx.Sort{{Field: "addr"}}))
+	that.Error(dao.Error)
+
+	that.Equal([]personMap{{"3", 20, "a"}, {"1", 30, "b"}, {"2", 10, "c"}},
+		dao.ListCond(sortCond{Sort: sqlx.Sort{{Field: "addr"}}}))
+	that.Equal([]personMap{{"3", 20, "a"}},
+		dao.ListCond(sortCond{Addr: "%a%", Sort: sqlx.Sort{{Field: "age"}}}))
+
+	that.Equal([]personMap{{"3", 20, "a"}, {"1", 30, "b"}},
+		dao.ListByName(M{"age": 15, "sort": sqlx.Sort{{Field: "addr"}}}))
+	that.Equal([]personMap{{"1", 30, "b"}, {"3", 20, "a"}},
+		dao.ListByName(M{"age": 15, "sort": sqlx.Sort{{Field: "age", Desc: true}}}))
+
+	that.Nil(dao.ListByName(M{"age": 15, "sort": sqlx.Sort{{Field: "age; drop table person"}}}))
+	that.Error(dao.Error)
+
+	that.Nil(dao.ListByName(M{"age": 15, "sort": sqlx.Sort{{Field: "addr"}}, "by": sqlx.Sort{{Field: "age"}}}))
+	that.Contains(dao.Error.Error(), "ambiguous sort of ListByAge, 2 Sort args found")
+
+	badDao := &struct {
+		List func(badSortCond) []personMap `sql:"select id, age, addr from person"`
+	}{}
+	that.Error(sqlx.CreateDao(badDao, sqlx.WithDB(openDB(t))))
+}
+
+type badSortCond struct {
+	Sort sqlx.Sort `sort:"addr=lower(addr"`
+}
+
+func TestParseSort(t *testing.T) {
+	that := assert.New(t)
+
+	sort, err := sqlx.ParseSort("name, -age, +id, created desc, updated asc")
+	that.Nil(err)
+	that.Equal(sqlx.Sort{{Field: "name"}, {Field: "age", Desc: true}, {Field: "id"},
+		{Field: "created", Desc: true}, {Field: "updated"}}, sort)
+	that.Equal("name asc, age desc, id asc, created desc, updated asc", sort.String())
+
+	_, err = sqlx.ParseSort("name up")
+	that.Error(err)
+}
+
+type partCond struct {
+	Addr  string     `sql:"addr like ?"`
+	Age   int        `sql:"or age > ?"`
+	Limit sqlx.Limit `sql:"limit ?,?"`
+}
+
+type havingCond struct {
+	Min int `sql:"having count(*) >= ?"`
+}
+
+type personPartDao struct {
+	CreateTable func()          `sql:"create table person(id varchar(100), age int, addr varchar(10))"`
+	Add         func(personMap) `sql:"insert into person(id, age, addr) values(:id, :age, :addr)"`
+
+	List      func(partCond, string) []personMap  `sql:"select id, age, addr from person where id > :2 or id = '0' order by id desc"`
+	CountAddr func(havingCond) []string           `sql:"select addr from person group by addr order by addr"`
+	Bad       func(partCond) ([]personMap, error) `sql:"select id, age, addr from person where"`
+
+	// where id > ? and addr like ? order by id desc limit ?
+	Top func(queryCond, string, int) []personMap `sql:"select id, age, addr from person where id > :2 order by id desc limit :3"`
+}
+
+func TestFieldParts(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personPartDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t))))
+
+	dao.CreateTable()
+	dao.Add(personMap{ID: "1", Age: 30, Addr: "b"})
+	dao.Add(personMap{ID: "2", Age: 10, Addr: "a"})
+	dao.Add(personMap{ID: "3", Age: 20, Addr: "a"})
+	dao.Add(personMap{ID: "4", Age: 40, Addr: "c"})
+
+	that.Equal([]personMap{{"4", 40, "c"}, {"3", 20, "a"}, {"2", 10, "a"}}, dao.List(partCond{}, "1"))
+	that.Equal([]personMap{{"3", 20, "a"}, {"2", 10, "a"}}, dao.List(partCond{Addr: "a"}, "1"))
+	// where (id > ? or id = '0') and (addr like ? or age > ?) order by id desc limit ?, ?
+	that.Equal([]personMap{{"4", 40, "c"}, {"3", 20, "a"}, {"2", 10, "a"}}, dao.List(partCond{Addr: "a", Age: 30}, "1"))
+	that.Equal([]personMap{{"3", 20, "a"}}, dao.List(partCond{Addr: "a", Age: 30,
+		Limit: sqlx.Limit{Offset: 1, Length: 1}}, "1"))
+
+	that.Equal([]personMap{{"3", 20, "a"}}, dao.Top(queryCond{Addr: "a"}, "1", 1))
+
+	that.Equal([]string{"a", "b", "c"}, dao.CountAddr(havingCond{}))
+	that.Equal([]string{"a"}, dao.CountAddr(havingCond{Min: 2}))
+
+	_, err := dao.Bad(partCond{Addr: "a"})
+	that.Error(err)
+}
+
+type qbeFilter struct {
+	Addr   string   `col:"addr" op:"like"`
+	MinAge int      `col:"age" op:">="`
+	IDs    []string `col:"id" op:"in"`
+	Ages   []int    `col:"age" op:"between"`
+	Age    *int     `col:"age"`
+	Note   *string  `col:"note,omitempty"`
+}
+
+type qbeNullFilter struct {
+	Note *string `col:"note,nullable"`
+}
+
+type personQBEDao struct {
+	CreateTable func()                            `sql:"create table person(id varchar(100), age int, addr varchar(10), note varchar(10))"`
+	Add         func(M)                           `sql:"insert into person(id, age, addr, note) values(:id, :age, :addr, :note)"`
+	Find        func(qbeFilter) ([]string, error) `sql:"select id from person order by id"`
+	FindNull    func(qbeNullFilter) []string      `sqlName:"Find"`
+	Bad         func(qbeBadFilter) error          `sql:"select id from person"`
+}
+
+type qbeBadFilter struct {
+	X int `col:"x" op:"in"`
+}
+
+type qbeNamedFilter struct {
+	Addr   string
+	MinAge int      `col:"age" op:">="`
+	IDs    []string `col:"id" op:"in"`
+}
+
+type personQBENamedDao struct {
+	Find func(qbeNamedFilter) []string `sql:"select id from person where addr = :addr order by id"`
+}
+
+func TestQBE(t *testing.T) {
+	that := assert.New(t)
+
+	db := openDB(t)
+	dao := &personQBEDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db)))
+
+	dao.CreateTable()
+	dao.Add(M{"id": "1", "age": 0, "addr": "b", "note": "x"})
+	dao.Add(M{"id": "2", "age": 10, "addr": "a", "note": nil})
+	dao.Add(M{"id": "3", "age": 20, "addr": "a", "note": "y"})
+	dao.Add(M{"id": "4", "age": 40, "addr": "c", "note": nil})
+
+	find := func(f qbeFilter) []string {
+		ids, err := dao.Find(f)
+		that.Nil(err)
+
+		return ids
+	}
+
+	zero, empty, x := 0, "", "x"
+
+	that.Equal([]string{"1", "2", "3", "4"}, find(qbeFilter{}))
+	that.Equal([]string{"2", "3"}, find(qbeFilter{Addr: "a"}))
+	that.Equal([]string{"3"}, find(qbeFilter{Addr: "a", MinAge: 20}))
+	that.Equal([]string{"1", "4"}, find(qbeFilter{IDs: []string{"1", "4"}}))
+	that.Equal([]string{"2", "3"}, find(qbeFilter{Ages: []int{5, 25}}))
+	that.Equal([]string{"1"}, find(qbeFilter{Age: &zero}))
+	that.Equal([]string{"1", "2", "3", "4"}, find(qbeFilter{Note: &empty}))
+	that.Equal([]string{"1"}, find(qbeFilter{Note: &x}))
+
+	that.Equal([]string{"2", "4"}, dao.FindNull(qbeNullFilter{}))
+	that.Equal([]string{"1"}, dao.FindNull(qbeNullFilter{Note: &x}))
+
+	// the empty non-nil slice of in matches nothing.
+	that.Equal([]string{}, find(qbeFilter{IDs: []string{}}))
+
+	_, err := dao.Find(qbeFilter{Ages: []int{1}})
+	that.Error(err)
+	that.Error(dao.Bad(qbeBadFilter{X: 1}))
+
+	// the filter fields also work with the named binds.
+	namedDao := &personQBENamedDao{}
+	that.Nil(sqlx.CreateDao(namedDao, sqlx.WithDB(db)))
+	that.Equal([]string{"2", "3"}, namedDao.Find(qbeNamedFilter{Addr: "a"}))
+	that.Equal([]string{"3"}, namedDao.Find(qbeNamedFilter{Addr: "a", MinAge: 20}))
+	that.Equal([]string{"2"}, namedDao.Find(qbeNamedFilter{Addr: "a", IDs: []string{"1", "2"}}))
+	that.Equal([]string{}, namedDao.Find(qbeNamedFilter{Addr: "a", IDs: []string{}}))
+}
+
+const dotSQLFor = `
+-- name: CreateTable
+create table person(id varchar(100), age int);
+
+-- name: AddAll
+insert into person(id, age) values
+-- for p in _1 sep ","
+(:p.id, :p.age)
+-- end
+;
+
+-- name: ListByIDs
+select id, age from person where id in (
+-- for id in ids sep ","
+:id
+-- end
+) order by id;
+
+-- name: ListByAges
+select id, age from person where
+-- for age, i in _1 sep " or "
+(age = :age and :i >= 0)
+-- end
+order by id;
+`
+
+type personForDao struct {
+	CreateTable func()
+	AddAll      func([]person) int
+	ListByIDs   func(M) []person
+	ListByAges  func([]int) []person
+}
+
+func TestDotSQLFor(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personForDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLFor)))
+
+	dao.CreateTable()
+	that.Equal(3, dao.AddAll([]person{{"1", 10}, {"2", 20}, {"3", 30}}))
+
+	that.Equal([]person{{"1", 10}, {"3", 30}}, dao.ListByIDs(M{"ids": []string{"3", "1"}}))
+	that.Equal([]person{{"2", 20}, {"3", 30}}, dao.ListByAges([]int{30, 20}))
+}
+
+const dotSQLWhereSet = `
+-- name: CreateTable
+create table person(id varchar(100), age int, addr varchar(10));
+
+-- name: Add
+insert into person(id, age, addr) values(:id, :age, :addr);
+
+-- name: Find
+select id from person
+-- where
+-- if id != ""
+and id = :id
+-- end
+-- if age > 0
+or age = :age
+-- end
+-- end
+order by id;
+
+-- name: Update
+update person
+-- set
+-- if age > 0
+age = :age,
+-- end
+-- if addr != ""
+addr = :addr,
+-- end
+-- end
+where id = :id;
+`
+
+type personWhereSetDao struct {
+	CreateTable func()
+	Add         func(M)
+	Find        func(M) []string
+	Update      func(M) int
+	GetAddr     func(string) string `sql:"select addr from person where id = :1"`
+}
+
+func TestDotSQLWhereSet(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personWhereSetDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLWhereSet)))
+
+	dao.CreateTable()
+	dao.Add(M{"id": "1", "age": 10, "addr": "a"})
+	dao.Add(M{"id": "2", "age": 20, "addr": "b"})
+
+	that.Equal([]string{"1", "2"}, dao.Find(M{"id": "", "age": 0}))
+	that.Equal([]string{"2"}, dao.Find(M{"id": "", "age": 20}))
+	that.Equal([]string{"1", "2"}, dao.Find(M{"id": "1", "age": 20}))
+
+	that.Equal(1, dao.Update(M{"id": "1", "age": 0, "addr": "x"}))
+	that.Equal("x", dao.GetAddr("1"))
+}
+
+const dotSQLSwitch = `
+-- name: CreateTable
+create table person(id varchar(100), age int, addr varchar(10));
+
+-- name: Add
+insert into person(id, age, addr) values(:id, :age, :addr);
+
+-- name: ListBy
+select id from person
+-- switch _1
+-- case 'age', 'oldest'
+order by age desc
+-- case 'id'
+order by id
+-- default
+order by addr
+-- end
+;
+`
+
+type personSwitchDao struct {
+	CreateTable func()
+	Add         func(M)
+	ListBy      func(string) []string
+}
+
+func TestDotSQLSwitch(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personSwitchDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLSwitch)))
+
+	dao.CreateTable()
+	dao.Add(M{"id": "1", "age": 10, "addr": "a"})
+	dao.Add(M{"id": "2", "age": 30, "addr": "c"})
+	dao.Add(M{"id": "3", "age": 20, "addr": "b"})
+
+	that.Equal([]string{"2", "3", "1"}, dao.ListBy("age"))
+	that.Equal([]string{"2", "3", "1"}, dao.ListBy("oldest"))
+	that.Equal([]string{"1", "2", "3"}, dao.ListBy("id"))
+	that.Equal([]string{"1", "3", "2"}, dao.ListBy("addr"))
+}
+
+type personFSDao struct {
+	CreateTable func()
+	AddAll      func(...person)
+	ListAll     func() []person `sqlName:"d3.ListAll"`
+}
+
+func TestDaoWithSQLFS(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personFSDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLFS(os.DirFS("testdata"), "d3.sql")))
+
+	dao.CreateTable()
+	dao.AddAll(person{"300", 300}, person{"400", 400})
+	that.Equal([]person{{"300", 300}, {"400", 400}}, dao.ListAll())
+
+	that.Error(sqlx.CreateDao(&personFSDao{}, sqlx.WithDB(openDB(t)), sqlx.WithSQLFS(os.DirFS("testdata"), "none/*.sql")))
+}
+
+type personWatchDao struct {
+	CreateTable func()
+	Add         func(person)
+	ListAll     func() []person
+	ListAdults  func(person) []person
+}
+
+func TestDaoWithSQLWatcher(t *testing.T) {
+	that := assert.New(t)
+
+	sqlFile := filepath.Join(t.TempDir(), "watch.sql")
+	writeSQL := func(order string, modTime time.Time) {
+		that.Nil(os.WriteFile(sqlFile, []byte(`
+-- name: CreateTable
+create table person(id varchar(100), age int);
+
+-- name: Add
+insert into person(id, age) values(:id, :age);
+
+-- name: ListAll
+select id, age from person order by `+order+`;
+
+-- name: ListAdults
+select id, age from person where id <> :id
+-- if isAdult(age)
+and age >= 18
+-- end
+order by `+order+`;
+`), 0o600))
+		that.Nil(os.Chtimes(sqlFile, modTime, modTime))
+	}
+
+	now := time.Now()
+	writeSQL("id", now)
+
+	w, err := sqlx.NewDotSQLFileWatcher(sqlFile)
+	that.Nil(err)
+
+	w.DotSQL().Use(sqlx.ExprFunc("isAdult", func(params ...interface{}) (interface{}, error) {
+		return params[0].(int) >= 18, nil
+	}, new(func(int) bool)))
+
+	var reloadErr error
+	w.OnError = func(err error) { reloadErr = err }
+
+	dao := &personWatchDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLWatcher(w)))
+
+	dao.CreateTable()
+	dao.Add(person{"1", 20})
+	dao.Add(person{"2", 10})
+	that.Equal([]person{{"1", 20}, {"2", 10}}, dao.ListAll())
+
+	writeSQL("age", now.Add(time.Second))
+	w.Check()
+	that.Nil(reloadErr)
+	that.Equal(uint64(1), w.Version())
+	that.Equal([]person{{"2", 10}, {"1", 20}}, dao.ListAll())
+	that.Equal([]person{{"1", 20}}, dao.ListAdults(person{ID: "x", Age: 20}))
+	that.Equal([]person{{"2", 10}, {"1", 20}}, dao.ListAdults(person{ID: "x", Age: 10}))
+
+	writeSQL("age\n-- if id\n", now.Add(2*time.Second))
+	w.Check()
+	that.Error(reloadErr)
+	that.Equal(uint64(1), w.Version())
+	that.Equal([]person{{"2", 10}, {"1", 20}}, dao.ListAll())
+}
+
+type dbTypeDao struct {
+	Now func() string
+}
+
+func TestDaoDBType(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &dbTypeDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(`
+-- name: Now
+select 'default';
+
+-- name: Now dbtype: sqlite
+select 'sqlite';
+
+-- name: Now dbtype: mysql
+select 'mysql';
+`)))
+
+	that.Equal("sqlite", dao.Now())
+}
+
+func TestDaoDBTypeLazy(t *testing.T) {
+	that := assert.New(t)
+
+	var db *sql.DB
+
+	dao := &dbTypeDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDBGetter(sqlx.GetDBFn(func() *sql.DB { return db })), sqlx.WithSQLStr(`
+-- name: Now
+select 'default';
+
+-- name: Now dbtype: sqlite
+select 'sqlite';
+`)))
+
+	db = openDB(t)
+	that.Equal("sqlite", dao.Now())
+
+	var names []string
+
+	custom := &dbTypeDao{}
+	that.Nil(sqlx.CreateDao(custom, sqlx.WithDB(db), sqlx.CreateDaoOptFn(func(opt *sqlx.CreateDaoOpt) {
+		opt.DotSQL = func(name string) (sqlx.SQLPart, error) {
+			names = append(names, name)
+			return (&sqlx.DotSQLItem{Name: name, Content: []string{"select 'custom'"}}).DynamicSQL()
+		}
+	})))
+
+	that.Equal("custom", custom.Now())
+	that.Equal([]string{"Now"}, names)
+}
+
+const dotSQLExpr = `
+-- name: CreateTable
+create table person(id varchar(100), age int);
+
+-- name: AddAll
+insert into person(id, age) values(:id, :age);
+
+-- name: Find
+select id, age from person
+-- where
+-- if isNotBlank(id)
+and id = :id
+-- end
+-- if isAdult(age)
+and age >= 18
+-- end
+-- end
+order by id;
+`
+
+type personExprDao struct {
+	CreateTable func()
+	AddAll      func(...person)
+	Find        func(person) []person
+}
+
+type personBadExprDao struct {
+	List func(person) []person
+}
+
+type personNoArgExprDao struct {
+	List func(int, int) []person
+}
+
+func TestDotSQLExprFunc(t *testing.T) {
+	that := assert.New(t)
+
+	isAdult := sqlx.ExprFunc("isAdult", func(params ...interface{}) (interface{}, error) {
+		return params[0].(int) >= 18, nil
+	}, new(func(int) bool))
+
+	dao := &personExprDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLExpr), sqlx.WithDotSQLOptions(isAdult)))
+
+	dao.CreateTable()
+	dao.AddAll(person{"1", 10}, person{"2", 20}, person{"3", 30})
+
+	that.Equal([]person{{"2", 20}, {"3", 30}}, dao.Find(person{Age: 20}))
+	that.Equal([]person{{"1", 10}}, dao.Find(person{ID: "1", Age: 10}))
+
+	err := sqlx.CreateDao(&personBadExprDao{}, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(`
+-- name: List
+select id from person
+-- if age == 'x'
+where age = :age
+-- end
+`))
+	that.Error(err)
+	that.Contains(err.Error(), "compile expressions of List error line 4: invalid operation: == (mismatched types int and string)")
+
+	err = sqlx.CreateDao(&personNoArgExprDao{}, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(`
+-- name: List
+select id from person
+-- if age > 0
+where age = :age
+-- end
+`))
+	that.Error(err)
+	that.Contains(err.Error(), "required named varialbes")
+}
+
+type logRow struct {
+	ID  string
+	Msg string
+}
+
+type logIdentDao struct {
+	CreateTable func(month string)
+	Add         func(month string, id, msg string)
+	List        func(month string) ([]logRow, error)
+}
+
+func TestDaoIdents(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &logIdentDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(`
+-- name: CreateTable ident: ^log_\d{6}$
+create table #{'log_' + _1}(id varchar(10), msg varchar(10));
+
+-- name: Add ident: ^log_\d{6}$
+insert into #{'log_' + _1}(id, msg) values(:2, :3);
+
+-- name: List ident: ^log_\d{6}$
+select id, msg from #{'log_' + _1} order by id;
+`)))
+
+	dao.CreateTable("202610")
+	dao.CreateTable("202611")
+	dao.Add("202610", "1", "oct")
+	dao.Add("202611", "2", "nov")
+
+	logs, err := dao.List("202610")
+	that.Nil(err)
+	that.Equal([]logRow{{"1", "oct"}}, logs)
+
+	logs, err = dao.List("202611")
+	that.Nil(err)
+	that.Equal([]logRow{{"2", "nov"}}, logs)
+
+	_, err = dao.List("202610 union select name, sql from sqlite_master")
+	that.Error(err)
+}
+
+type personErrorsDao struct {
+	BadTable func(person) ([]person, error)
+}
+
+func TestDaoErrorPosition(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personErrorsDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLFile("testdata/errors.sql")))
+
+	_, err := dao.BadTable(person{ID: "1"})
+
+	var dotErr *sqlx.DotSQLError
+	that.True(errors.As(err, &dotErr))
+	that.Equal(23, dotErr.Line)
+	that.True(strings.HasPrefix(err.Error(), "testdata/errors.sql:23: execute select * from no_such_table"))
+}
+
+type personDefaultsDao struct {
+	CreateTable func()                                `sql:"create table person(id varchar(100), age int)"`
+	Add         func(map[string]interface{})          `sql:"insert into person(id, age) values(:id, :age|18)"`
+	List        func(map[string]interface{}) []person `sql:"select id, age from person where age >= :min|0 order by id limit :limit|100"`
+	Find        func(person) ([]person, error)        `sql:"select id, age from person where id = :id and age = :agee"`
+}
+
+func TestDaoBindDefaults(t *testing.T) {
+	that := assert.New(t)
+
+	dao := &personDefaultsDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t))))
+
+	dao.CreateTable()
+	dao.Add(map[string]interface{}{"id": "1"})
+	dao.Add(map[string]interface{}{"id": "2", "age": 30})
+	dao.Add(map[string]interface{}{"id": "3", "age": nil})
+
+	that.Equal([]person{{"1", 18}, {"2", 30}, {"3", 18}}, dao.List(map[string]interface{}{}))
+	that.Equal([]person{{"2", 30}}, dao.List(map[string]interface{}{"min": 20}))
+	that.Equal([]person{{"1", 18}}, dao.List(map[string]interface{}{"limit": 1}))
+
+	_, err := dao.Find(person{ID: "1"})
+	that.EqualError(err, "named param :agee is not found in sqlx_test.person")
+}
+
+type MappingBase struct {
+	ID int64
+}
+
+type mappingEmbedded struct {
+	MappingBase
+	Email string `name:"email_addr"`
+}
+
+func TestDaoStructMapping(t *testing.T) {
+	that := assert.New(t)
+
+	type embeddedDao struct {
+		CreateTable func()                   `sql:"create table mapping_row(id int, email_addr varchar(100))"`
+		Add         func(mappingEmbedded)    `sql:"insert into mapping_row(id, email_addr) values(:id, :email_addr)"`
+		ListAll     func() []mappingEmbedded `sql:"select id, email_addr from mapping_row order by id"`
+	}
+
+	dao := &embeddedDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t))))
+
+	dao.CreateTable()
+	dao.Add(mappingEmbedded{MappingBase{2}, "b@c.d"})
+	dao.Add(mappingEmbedded{MappingBase{1}, "a@c.d"})
+
+	for i := 0; i < 2; i++ { // the second run hits the cached mapping
+		that.Equal([]mappingEmbedded{{MappingBase{1}, "a@c.d"}, {MappingBase{2}, "b@c.d"}}, dao.ListAll())
+	}
+
+	// the field types are copied from the cached ones.
+	sv := sqlx.MakeStructValue(reflect.ValueOf(mappingEmbedded{}))
+	sv.FieldTypes[1].Tag = `name:"mail"`
+	that.Equal(`name:"email_addr"`, string(sqlx.MakeStructValue(reflect.ValueOf(mappingEmbedded{})).FieldTypes[1].Tag))
+	that.Equal([]mappingEmbedded{{MappingBase{1}, "a@c.d"}, {MappingBase{2}, "b@c.d"}}, dao.ListAll())
+}
+
+type planDao struct {
+	CreateTable func()                            `sql:"create table person(id varchar(100), age int)"`
+	Add         func(person)                      `sql:"insert into person(id, age) values(:id, :age)"`
+	Find        func(id string) []person          `sql:"select id, age from person where id = :1"`
+	FindDynamic func(id string, age int) []person `sql:"select id, age from person where id = :1 /* if _2 > 0 */ and age = :2 /* end */"`
+}
+
+func TestDaoPlanReuse(t *testing.T) {
+	that := assert.New(t)
+
+	dollarDB, err := sql.Open("sqlite3_dollar", ":memory:")
+	that.Nil(err)
+
+	dbs := []*sql.DB{openSingleDB(t), dollarDB}
+	current := 0
+
+	dao := &planDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDBGetter(sqlx.GetDBFn(func() *sql.DB { return dbs[current] }))))
+
+	for current = range dbs {
+		dao.CreateTable()
+		dao.Add(person{"1", 10})
+		dao.Add(person{"2", 20})
+
+		for i := 0; i < 2; i++ {
+			that.Equal([]person{{"2", 20}}, dao.Find("2"))
+			that.Equal([]person{{"1", 10}}, dao.FindDynamic("1", 0))
+			that.Equal([]person{{"1", 10}}, dao.FindDynamic("1", 10))
+			that.Equal([]person{}, dao.FindDynamic("1", 20))
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package sqlx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// bindVar is the bind var in the SQL, like :name, :1, : or :limit|100.
+type bindVar struct {
+	Name string
+	// Default is the default value like 100 of :limit|100, which is bound when the named value is absent.
+	Default    interface{}
+	HasDefault bool
+}
+
+// replaceBinds replaces the bind vars in the SQL by the replace func.
+// The string literals, the quoted identifiers, the comments, the :: casts like ::text and the := assignments
+// are skipped, \: is unescaped to a plain :, and the quoted bind like ':name' is still a bind var for compatibility.
+// The default value of the named bind var follows |, like :limit|100, :name|'anonymous' or :flag|true.
+func replaceBinds(s string, replace func(b bindVar) string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(s); {
+		switch c := s[i]; {
+		case c == '\\' && i+1 < len(s) && s[i+1] == ':':
+			out.WriteByte(':')
+			i += 2
+		case c == '\'':
+			end := literalEnd(s, i, '\'')
+			if end-i > 2 && s[end-1] == '\'' && s[i+1] == ':' && isBindName(s[i+2:end-1]) {
+				out.WriteString(replace(bindVar{Name: s[i+2 : end-1]}))
+			} else {
+				out.WriteString(s[i:end])
+			}
+
+			i = end
+		case nonCodeEnd(s, i) > i:
+			end := nonCodeEnd(s, i)
+			out.WriteString(s[i:end])
+			i = end
+		case c == ':' && i+1 < len(s) && (s[i+1] == ':' || s[i+1] == '='):
+			out.WriteString(s[i : i+2])
+			i += 2
+
+			for i < len(s) && s[i] == ':' { // like :::
+				out.WriteByte(':')
+				i++
+			}
+		case c == ':':
+			b, end := scanBindVar(s, i)
+			out.WriteString(replace(b))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// nonCodeEnd returns the end of the literal, the quoted identifier or the comment starting at i, or i when none starts.
+func nonCodeEnd(s string, i int) int {
+	switch {
+	case s[i] == '"' || s[i] == '`' || s[i] == '\'':
+		return literalEnd(s, i, s[i])
+	case strings.HasPrefix(s[i:], "--"):
+		if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+			return i + end
+		}
+
+		return len(s)
+	case strings.HasPrefix(s[i:], "/*"):
+		if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+
+		return len(s)
+	default:
+		return i
+	}
+}
+
+// literalEnd returns the end of the literal starting at i, the quote is escaped by doubling it or by \.
+func literalEnd(s string, i int, quote byte) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case quote:
+			if j+1 < len(s) && s[j+1] == quote {
+				j++
+				continue
+			}
+
+			return j + 1
+		}
+	}
+
+	return len(s)
+}
+
+func isBindName(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isWordChar(s[i]) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+// scanBindVar scans the bind var starting at the colon s[i], and returns the end of it.
+func scanBindVar(s string, i int) (bindVar, int) {
+	j := i + 1
+	for j < len(s) && isWordChar(s[j]) {
+		j++
+	}
+
+	b := bindVar{Name: s[i+1 : j]}
+
+	// the default is only for the named bind var, and || is the concatenation operator.
+	if b.Name == "" || j+1 >= len(s) || s[j] != '|' || s[j+1] == '|' {
+		return b, j
+	}
+
+	k := j + 1
+	if s[k] == '\'' {
+		k = literalEnd(s, k, '\'')
+	} else {
+		for k < len(s) && (isWordChar(s[k]) || s[k] == '.' || s[k] == '-' && k == j+1) {
+			k++
+		}
+	}
+
+	if k == j+1 {
+		return b, j
+	}
+
+	b.Default, b.HasDefault = parseBindDefault(s[j+1:k]), true
+
+	return b, k
+}
+
+// parseBindDefault parses the default value like 100, 1.5, true, null or 'anonymous'.
+func parseBindDefault(s string) interface{} {
+	if l := len(s); l >= 2 && s[0] == '\'' && s[l-1] == '\'' {
+		return strings.ReplaceAll(s[1:l-1], "''", "'")
+	}
+
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return i
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		return f
+	}
+
+	switch strings.ToLower(s) {
+	case "true":
+		return true
+	case "false":
+		return false
+	case "null":
+		return nil
+	}
+
+	return s
+}
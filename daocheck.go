@@ -0,0 +1,234 @@
+package sqlx
+
+import (
+	"database/sql"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/bingoohuang/gor"
+	"github.com/bingoohuang/sqlparser/sqlparser"
+)
+
+// CheckDao checks the result mappings of the dao query funcs against the live schema of the db,
+// like in CI against a SQLite/MySQL fixture. Every select with a struct result is run with LIMIT 0 and NULL binds,
+// and its column types are checked for the columns not mapped to any field, the fields missing in the columns,
+// and the type mismatches like a nullable column into a non-pointer int.
+// All the problems are reported at once by a *DaoValidationError.
+func CheckDao(dao interface{}, db *sql.DB, createDaoOpts ...CreateDaoOpter) error {
+	v, option, err := createDaoOption(dao, append(createDaoOpts, WithDB(db)))
+	if err != nil {
+		return err
+	}
+
+	var problems []error
+
+	structValue := MakeStructValue(v)
+	for i := 0; i < structValue.NumField; i++ {
+		f := structValue.FieldByIndex(i)
+
+		if f.PkgPath != "" /* not exportable */ || f.Kind != reflect.Func {
+			continue
+		}
+
+		for _, err := range checkFunc(db, option, f) {
+			problems = append(problems, fmt.Errorf("%s: %w", f.Name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &DaoValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+func checkFunc(db *sql.DB, option *CreateDaoOpt, f StructField) []error {
+	parsed, err := option.parseField(f)
+	if err != nil {
+		return []error{err}
+	}
+
+	out := resultStructOf(f)
+	if !parsed.IsQuery || out == nil {
+		return nil
+	}
+
+	v := &funcValidator{parsed: parsed, f: f}
+
+	variants, err := v.sqlVariants()
+	if err != nil {
+		return []error{err}
+	}
+
+	// the SQL with the identifiers like ${table} can not be run before the calling.
+	if len(variants) == 0 || strings.Contains(variants[0], identPlaceholder) {
+		return nil
+	}
+
+	columns, err := queryColumnTypes(db, variants[0])
+	if err != nil {
+		return []error{err}
+	}
+
+	return checkColumns(columns, out, !nullableUnknownDrivers[option.getDBType()])
+}
+
+// resultStructOf returns the struct type of the single result like T, *T or []T, or nil.
+func resultStructOf(f StructField) reflect.Type {
+	numOut := f.Type.NumOut()
+	if numOut > 0 && gor.IsError(f.Type.Out(numOut-1)) {
+		numOut--
+	}
+
+	if numOut != 1 {
+		return nil
+	}
+
+	out := f.Type.Out(0)
+	if k := out.Kind(); k == reflect.Slice || k == reflect.Ptr {
+		out = out.Elem()
+	}
+
+	if out.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return out
+}
+
+// nolint:gochecknoglobals
+var (
+	checkBindRe = regexp.MustCompile(`\?`)
+	// nullableUnknownDrivers report every column nullable, even the not null ones, like sqlite3.
+	nullableUnknownDrivers = map[string]bool{"sqlite3": true}
+)
+
+// queryColumnTypes runs the select with LIMIT 0 and NULL binds to get its column types.
+func queryColumnTypes(db *sql.DB, s string) ([]*sql.ColumnType, error) {
+	binds := 0
+	marked := checkBindRe.ReplaceAllStringFunc(replaceBinds(s, func(bindVar) string { return "?" }), func(string) string {
+		binds++
+		return bindVarMark(binds - 1)
+	})
+
+	stmt, err := sqlparser.Parse(marked)
+	if err != nil {
+		return nil, fmt.Errorf("parse sql %s error %w", s, err)
+	}
+
+	if err := setLimit(stmt, &sqlparser.Limit{Rowcount: sqlparser.NewIntVal([]byte("0"))}); err != nil {
+		return nil, err
+	}
+
+	d := DialectOfDB(db)
+	query, _ := formatBindVars(stmt, d)
+	query = d.Placeholder().Rebind(query)
+
+	rows, err := db.Query(query, make([]interface{}, binds)...)
+	if err != nil {
+		return nil, fmt.Errorf("execute %s error %w", query, err)
+	}
+
+	defer rows.Close()
+
+	return rows.ColumnTypes()
+}
+
+// checkColumns checks the columns with the fields of the result struct,
+// the nullable columns are checked when the nullability reported by the driver is reliable.
+func checkColumns(columns []*sql.ColumnType, out reflect.Type, checkNullable bool) []error {
+	var problems []error
+
+	mapped := make(map[string]bool)
+
+	for _, c := range columns {
+		field, ok := fieldByColumn(out, c.Name())
+
+		if !ok {
+			problems = append(problems, fmt.Errorf("column %s is not mapped to any field of %v", // nolint:goerr113
+				c.Name(), out))
+
+			continue
+		}
+
+		mapped[field.Name] = true
+
+		if err := checkColumnType(c, field, checkNullable); err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	for _, f := range reflect.VisibleFields(out) {
+		if isEmbeddedStruct(f) || f.PkgPath != "" || mapped[f.Name] {
+			continue
+		}
+
+		// the field shadowed by the shallower one of the same name is not mapped.
+		if visible, _ := out.FieldByName(f.Name); len(visible.Index) != len(f.Index) {
+			continue
+		}
+
+		problems = append(problems, fmt.Errorf("field %s of %v is missing in the columns", f.Name, out)) // nolint:goerr113
+	}
+
+	return problems
+}
+
+// isEmbeddedStruct tells whether the field is an embedded struct whose fields are promoted to be mapped instead.
+func isEmbeddedStruct(f reflect.StructField) bool {
+	if !f.Anonymous {
+		return false
+	}
+
+	t := f.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && t != timeType && !ImplSQLScanner(t)
+}
+
+// checkColumnType checks the column type is compatible with the field type.
+func checkColumnType(c *sql.ColumnType, field reflect.StructField, checkNullable bool) error {
+	ft := field.Type
+	if ft.Kind() == reflect.Ptr || ft.Kind() == reflect.Interface || ImplSQLScanner(ft) {
+		return nil
+	}
+
+	if nullable, ok := c.Nullable(); checkNullable && ok && nullable {
+		return fmt.Errorf("nullable column %s %s into non-pointer field %s %v", // nolint:goerr113
+			c.Name(), c.DatabaseTypeName(), field.Name, ft)
+	}
+
+	st := scanValueType(c.ScanType())
+	if st == nil || ft.Kind() == reflect.String {
+		return nil
+	}
+
+	isFieldTime := ft == timeType || timeType.ConvertibleTo(ft)
+	if st == timeType && !isFieldTime || isFieldTime && isNumberOrBool(st.Kind()) {
+		return fmt.Errorf("column %s %s mismatches field %s %v", // nolint:goerr113
+			c.Name(), c.DatabaseTypeName(), field.Name, ft)
+	}
+
+	return nil
+}
+
+func isNumberOrBool(k reflect.Kind) bool {
+	return k == reflect.Bool || k >= reflect.Int && k <= reflect.Float64
+}
+
+// scanValueType returns the value type of the scan type, like int64 of sql.NullInt64.
+func scanValueType(t reflect.Type) reflect.Type {
+	if t == nil || t.Kind() != reflect.Struct || t == timeType {
+		return t
+	}
+
+	if v, ok := t.FieldByName("Valid"); ok && t.NumField() == 2 {
+		return t.Field(1 - v.Index[0]).Type
+	}
+
+	return t
+}
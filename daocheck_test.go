@@ -0,0 +1,102 @@
+package sqlx_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/bingoohuang/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type checkPerson struct {
+	ID   string
+	Age  int
+	Born int
+	Nick string
+}
+
+type checkDao struct {
+	CreateTable func()                   `sql:"create table person(id varchar(100), age int, born datetime, addr text)"`
+	Find        func(string) checkPerson `sql:"select id, age, born, addr from person where id = :1"`
+	ListAll     func() []person          `sql:"select id, age from person order by id"`
+	Count       func() int               `sql:"select count(*) from person"`
+}
+
+func TestCheckDao(t *testing.T) {
+	that := assert.New(t)
+
+	db := openSingleDB(t)
+	dao := &checkDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db)))
+	dao.CreateTable()
+
+	err := sqlx.CheckDao(&checkDao{}, db)
+
+	var validationErr *sqlx.DaoValidationError
+
+	that.True(errors.As(err, &validationErr))
+	that.Len(validationErr.Problems, 3)
+	that.Contains(err.Error(), "Find: column born datetime mismatches field Born int")
+	that.Contains(err.Error(), "Find: column addr is not mapped to any field of sqlx_test.checkPerson")
+	that.Contains(err.Error(), "Find: field Nick of sqlx_test.checkPerson is missing in the columns")
+}
+
+type checkBase struct {
+	ID   string
+	Age  int
+	Nick string
+}
+
+type checkEmbedPerson struct {
+	checkBase
+	Addr string
+}
+
+type checkEmbedDao struct {
+	CreateTable func()                        `sql:"create table person(id varchar(100), age int, addr text)"`
+	Find        func(string) checkEmbedPerson `sql:"select id, age, addr from person where id = :1"`
+}
+
+func TestCheckDaoEmbedded(t *testing.T) {
+	that := assert.New(t)
+
+	db := openSingleDB(t)
+	dao := &checkEmbedDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db)))
+	dao.CreateTable()
+
+	err := sqlx.CheckDao(&checkEmbedDao{}, db)
+	that.EqualError(err, "dao validation found 1 problem(s):\n"+
+		"Find: field Nick of sqlx_test.checkEmbedPerson is missing in the columns")
+}
+
+type nullablePerson struct {
+	ID   string
+	Age  int
+	Born *time.Time
+}
+
+type nullableDao struct {
+	ListAll func() []nullablePerson `sql:"select id, age, born from person"`
+}
+
+func TestCheckDaoNullable(t *testing.T) {
+	that := assert.New(t)
+
+	db, mock, err := sqlmock.New()
+	that.Nil(err)
+
+	defer db.Close()
+
+	mock.ExpectQuery("select id, age, born from person limit 0").WillReturnRows(sqlmock.NewRowsWithColumnDefinition(
+		sqlmock.NewColumn("id").OfType("VARCHAR", "").Nullable(false),
+		sqlmock.NewColumn("age").OfType("INT", int64(0)).Nullable(true),
+		sqlmock.NewColumn("born").OfType("DATETIME", time.Time{}).Nullable(true)))
+
+	err = sqlx.CheckDao(&nullableDao{}, db)
+	that.EqualError(err, "dao validation found 1 problem(s):\n"+
+		"ListAll: nullable column age INT into non-pointer field Age int")
+	that.Nil(mock.ExpectationsWereMet())
+}
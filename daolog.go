@@ -57,6 +57,35 @@ func createDBGetter(v reflect.Value, option *CreateDaoOpt) {
 	option.DBGetter = GetDBFn(func() *sql.DB { return DB })
 }
 
+// lookupDBType returns the dbtype by the driver name of the db, to select the dotsql variants and the dialect.
+// The dbtype is detected once the db is available, which may be assigned after CreateDao,
+// false is returned before that.
+func (option *CreateDaoOpt) lookupDBType() (string, bool) {
+	if dbType, ok := option.dbType.Load().(string); ok {
+		return dbType, true
+	}
+
+	if option.DBGetter == nil {
+		return "", false
+	}
+
+	db := option.DBGetter.GetDB()
+	if db == nil {
+		return "", false
+	}
+
+	dbType := LookupDriverName(db.Driver())
+	option.dbType.Store(dbType)
+
+	return dbType, true
+}
+
+// getDBType returns the dbtype of the dao's driver, empty when it is unknown yet.
+func (option *CreateDaoOpt) getDBType() string {
+	dbType, _ := option.lookupDBType()
+	return dbType
+}
+
 func createLogger(v reflect.Value, option *CreateDaoOpt) {
 	if option.Logger != nil {
 		return
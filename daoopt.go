@@ -4,7 +4,9 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"io/fs"
 	"reflect"
+	"sync/atomic"
 
 	"github.com/bingoohuang/gor"
 	"github.com/bingoohuang/gor/defaults"
@@ -18,6 +20,7 @@ type CreateDaoOpt struct {
 
 	RowScanInterceptor RowScanInterceptor
 
+	// DotSQL looks up the SQL by name.
 	DotSQL func(name string) (SQLPart, error)
 
 	Logger DaoLogger
@@ -25,6 +28,17 @@ type CreateDaoOpt struct {
 	ErrSetter func(err error)
 
 	DBGetter DBGetter
+
+	QueryReplacer QueryReplacer
+
+	// variant looks up the variant of the SQL tagged by the dbtype of the dao's driver, set by the dotsql options.
+	variant   func(name, dbType string) (SQLPart, bool, error)
+	watcher   *DotSQLWatcher
+	dbType    atomic.Value // string
+	strict    bool
+	namedArgs bool
+	exprOpts  []DotSQLOption
+	err       error
 }
 
 // CreateDaoOpter defines the option pattern interface for CreateDaoOpt.
@@ -66,7 +80,7 @@ func WithSQLFile(sqlFile string) CreateDaoOpter {
 			panic(err)
 		}
 
-		opt.DotSQL = ds.Raw
+		opt.DotSQL, opt.variant = ds.Raw, ds.variant
 	})
 }
 
@@ -75,6 +89,58 @@ func WithDB(db *sql.DB) CreateDaoOpter {
 	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.DBGetter = MakeDB(db) })
 }
 
+// WithDBGetter imports a DBGetter, like a ReplicaSet.
+func WithDBGetter(getter DBGetter) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.DBGetter = getter })
+}
+
+// WithQueryReplacer specifies the QueryReplacer of the dao instead of the global SQLReplacer.
+func WithQueryReplacer(replacer QueryReplacer) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.QueryReplacer = replacer })
+}
+
+// WithSQLFS imports SQL queries from the files of fsys matched by the patterns, like sql/*.sql of embed.FS,
+// the loading error is returned by CreateDao.
+func WithSQLFS(fsys fs.FS, patterns ...string) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) {
+		ds, err := DotSQLLoadFS(fsys, patterns...)
+		if err != nil {
+			opt.err = err
+			return
+		}
+
+		opt.DotSQL, opt.variant = ds.Raw, ds.variant
+	})
+}
+
+// WithSQLWatcher imports SQL queries from the DotSQLWatcher,
+// the dao funcs reparse their SQLs on the next calls after the DotSQL is reloaded.
+func WithSQLWatcher(watcher *DotSQLWatcher) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) {
+		opt.DotSQL, opt.variant = watcher.Raw, watcher.variant
+		opt.watcher = watcher
+	})
+}
+
+// WithStrictValidation validates all the SQLs of the dao in CreateDao like ValidateDao,
+// and CreateDao fails with all the problems found.
+func WithStrictValidation() CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.strict = true })
+}
+
+// WithNamedArgs passes the named binds like :name as sql.Named args, for the drivers whose placeholders
+// support the named args, like @name of SQL Server and :name of Oracle, see RegisterPlaceholder.
+// The others are still passed positionally.
+func WithNamedArgs() CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.namedArgs = true })
+}
+
+// WithDotSQLOptions specifies the options to compile the expressions of the dao SQLs,
+// like the custom functions by ExprFunc.
+func WithDotSQLOptions(opts ...DotSQLOption) CreateDaoOpter {
+	return CreateDaoOptFn(func(opt *CreateDaoOpt) { opt.exprOpts = append(opt.exprOpts, opts...) })
+}
+
 // WithSQLStr imports SQL queries from the string.
 func WithSQLStr(s string) CreateDaoOpter {
 	return CreateDaoOptFn(func(opt *CreateDaoOpt) {
@@ -83,7 +149,7 @@ func WithSQLStr(s string) CreateDaoOpter {
 			panic(err)
 		}
 
-		opt.DotSQL = ds.Raw
+		opt.DotSQL, opt.variant = ds.Raw, ds.variant
 	})
 }
 
@@ -115,6 +181,10 @@ func applyCreateDaoOption(createDaoOpts []CreateDaoOpter) (*CreateDaoOpt, error)
 		v.ApplyCreateOpt(opt)
 	}
 
+	if opt.err != nil {
+		return nil, opt.err
+	}
+
 	if opt.Ctx == nil {
 		opt.Ctx = context.Background()
 	}
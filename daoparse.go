@@ -1,8 +1,10 @@
 package sqlx
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
-	"regexp"
+	"reflect"
 	"strconv"
 	"strings"
 
@@ -73,14 +75,62 @@ type SQLParsed struct {
 
 	fp     FieldParts
 	runSQL string
+
+	sortable sortable
+	// sortTags are the whitelists of the struct params by the sort tags of their Sort fields.
+	sortTags  map[reflect.Type]sortable
+	orderBy   sqlparser.OrderBy
+	varsOrder []int
+
+	primary bool
+	ctx     context.Context
+
+	shardKey      string
+	shardReplacer QueryReplacer
+
+	evalVars evalVars
+	// defaults are the default values of the named bind vars, like 100 of :limit|100.
+	defaults map[string]interface{}
+	// named tells the vars are passed as sql.Named args, see WithNamedArgs.
+	named bool
+	// plans memoizes the bind plans of the evaluated SQL, see usePlan.
+	plans *sqlPlans
+}
+
+// getCtx returns the context of the current call, or the context of the dao.
+func (p SQLParsed) getCtx() context.Context {
+	if p.ctx != nil {
+		return p.ctx
+	}
+
+	return p.opt.Ctx
 }
 
+// replaceQuery replaces the query by the dao's QueryReplacer (or the global SQLReplacer when not specified),
+// then by the QueryReplacer carried by the context, and then by the replacer of the routed shard.
 func (p SQLParsed) replaceQuery(query string) (string, error) {
-	if SQLReplacer == nil {
-		return query, nil
+	replacer := SQLReplacer
+	if p.opt != nil && p.opt.QueryReplacer != nil {
+		replacer = p.opt.QueryReplacer
 	}
 
-	return SQLReplacer.ReplacerQuery(query)
+	var ctxReplacer QueryReplacer
+	if p.opt != nil {
+		ctxReplacer = QueryReplacerOf(p.getCtx())
+	}
+
+	for _, r := range []QueryReplacer{replacer, ctxReplacer, p.shardReplacer} {
+		if r == nil {
+			continue
+		}
+
+		var err error
+		if query, err = r.ReplacerQuery(query); err != nil {
+			return "", err
+		}
+	}
+
+	return query, nil
 }
 
 func (p SQLParsed) isBindBy(by ...bindBy) bool {
@@ -93,8 +143,6 @@ func (p SQLParsed) isBindBy(by ...bindBy) bool {
 	return false
 }
 
-var sqlre = regexp.MustCompile(`'?:\w*'?`)
-
 type FieldParts struct {
 	fieldParts []FieldPart
 	fieldVars  []interface{}
@@ -120,22 +168,28 @@ func ParseSQL(name, stmt string) (*SQLParsed, error) {
 	return p, nil
 }
 
-func (p *SQLParsed) fastParseSQL(stmt string) error {
+// replaceBinds replaces the bind vars in the SQL by the mark func, and collects their names and defaults.
+func (p *SQLParsed) replaceBinds(s string, mark func(i int) string) string {
 	p.Vars = make([]string, 0)
-	p.RawStmt = sqlre.ReplaceAllStringFunc(stmt, func(v string) string {
-		if v[0:1] == "'" {
-			v = v[2:]
-		} else {
-			v = v[1:]
-		}
+	p.defaults = nil
+
+	return replaceBinds(s, func(b bindVar) string {
+		p.Vars = append(p.Vars, b.Name)
+
+		if b.HasDefault {
+			if p.defaults == nil {
+				p.defaults = make(map[string]interface{})
+			}
 
-		if v != "" && v[len(v)-1:] == "'" {
-			v = v[:len(v)-1]
+			p.defaults[b.Name] = b.Default
 		}
 
-		p.Vars = append(p.Vars, v)
-		return "?"
+		return mark(len(p.Vars) - 1)
 	})
+}
+
+func (p *SQLParsed) fastParseSQL(stmt string) error {
+	p.RawStmt = p.replaceBinds(stmt, func(int) string { return "?" })
 
 	var err error
 
@@ -144,56 +198,304 @@ func (p *SQLParsed) fastParseSQL(stmt string) error {
 		return err
 	}
 
-	_, p.IsQuery = IsQuerySQL(p.RawStmt)
+	p.IsQuery = p.dialect().IsQuery(FirstWord(p.RawStmt))
 	return nil
 }
 
 func (p *SQLParsed) parseSQL(runSQl string) error {
-	p.Vars = make([]string, 0)
-	p.runSQL = sqlre.ReplaceAllStringFunc(runSQl, func(v string) string {
-		if v[0:1] == "'" {
-			v = v[2:]
-		} else {
-			v = v[1:]
-		}
+	rewrite := len(p.fp.fieldParts) > 0 || len(p.orderBy) > 0
 
-		if v != "" && v[len(v)-1:] == "'" {
-			v = v[:len(v)-1]
+	var db *sql.DB
+	if p.opt != nil && p.opt.DBGetter != nil {
+		db = p.opt.DBGetter.GetDB()
+	}
+
+	if !rewrite && db != nil {
+		p.usePlan(db, runSQl)
+		return nil
+	}
+
+	p.runSQL = p.replaceBinds(runSQl, func(i int) string {
+		if rewrite {
+			return bindVarMark(i)
 		}
 
-		p.Vars = append(p.Vars, v)
 		return "?"
 	})
 
-	if len(p.fp.fieldParts) > 0 {
-		parsed, err := sqlparser.Parse(p.runSQL)
-		if err != nil {
-			panic(err)
+	for _, f := range p.fp.fieldParts {
+		p.Vars = append(p.Vars, f.VarMarks()...)
+		p.fp.fieldVars = append(p.fp.fieldVars, f.Vars()...)
+	}
+
+	if rewrite {
+		if err := p.rewriteSQL(); err != nil {
+			return err
+		}
+	}
+
+	if db != nil {
+		p.named = false
+		p.runSQL = convertSQLBindMarks(db, p.runSQL)
+	}
+
+	return nil
+}
+
+// rewriteSQL splices the field parts and the dynamic sort into the AST of the SQL,
+// and records the order of bind vars in the rewritten SQL.
+func (p *SQLParsed) rewriteSQL() error {
+	stmt, err := sqlparser.Parse(p.runSQL)
+	if err != nil {
+		return fmt.Errorf("parse sql %s error %w", p.runSQL, err)
+	}
+
+	if err := p.fp.splice(stmt, len(p.Vars)-len(p.fp.fieldVars)); err != nil {
+		return fmt.Errorf("splice field sql parts to %s error %w", p.runSQL, err)
+	}
+
+	if len(p.orderBy) > 0 {
+		if err := setOrderBy(stmt, p.orderBy); err != nil {
+			return err
 		}
+	}
+
+	p.runSQL, p.varsOrder = formatBindVars(stmt, p.dialect())
+
+	return nil
+}
+
+// reorderVars reorders the vars to the order of bind vars in the rewritten SQL.
+func (p *SQLParsed) reorderVars(vars []interface{}) []interface{} {
+	if p.varsOrder == nil {
+		return vars
+	}
+
+	ordered := make([]interface{}, len(p.varsOrder))
+	for i, index := range p.varsOrder {
+		ordered[i] = vars[index]
+	}
+
+	return ordered
+}
+
+const bindVarMarkPrefix = ":_sqlx_"
 
-		w, hasWhere := parsed.(sqlparser.IWhere)
-		if hasWhere {
-			hasWhere = w.GetWhere() != nil
+// bindVarMark makes the mark of the bind var which tells its original index.
+func bindVarMark(index int) string { return bindVarMarkPrefix + strconv.Itoa(index) }
+
+// formatBindVars formats the statement with ? placeholders and the limit clause of the dialect,
+// and returns the original indexes of bind vars in the order of the formatted SQL.
+func formatBindVars(stmt sqlparser.SQLNode, d Dialect) (string, []int) {
+	order := make([]int, 0)
+	buf := sqlparser.NewTrackedBuffer(func(buf *sqlparser.TrackedBuffer, node sqlparser.SQLNode) {
+		if l, ok := node.(*sqlparser.Limit); ok {
+			formatLimit(buf, d, l)
+			return
 		}
 
-		for i, f := range p.fp.fieldParts {
-			if f.JoinedSep {
-				if i == 0 && !hasWhere {
-					p.runSQL += " where " + f.PartSQL
-				} else {
-					p.runSQL += " and " + f.PartSQL
-				}
-			} else {
-				p.runSQL += " " + f.PartSQL
+		if v, ok := node.(*sqlparser.SQLVal); ok && v.Type == sqlparser.ValArg {
+			if s := string(v.Val); strings.HasPrefix(s, bindVarMarkPrefix) {
+				index, _ := strconv.Atoi(s[len(bindVarMarkPrefix):])
+				order = append(order, index)
+
+				buf.WriteString("?")
+
+				return
 			}
+		}
+
+		node.Format(buf)
+	})
+
+	buf.Myprintf("%v", stmt)
+
+	return buf.String(), order
+}
+
+const (
+	limitRowcountMark = "\x00r"
+	limitOffsetMark   = "\x00o"
+)
+
+// formatLimit formats the limit clause by the dialect, the bind vars are formatted in the order of the clause,
+// like limit ?, ? of offset, row count for MySQL, and limit ? offset ? of row count, offset for the others.
+func formatLimit(buf *sqlparser.TrackedBuffer, d Dialect, limit *sqlparser.Limit) {
+	if limit == nil {
+		return
+	}
+
+	offset := ""
+	if limit.Offset != nil {
+		offset = limitOffsetMark
+	}
+
+	exprs := map[string]sqlparser.Expr{limitRowcountMark: limit.Rowcount, limitOffsetMark: limit.Offset}
+	clause := " " + d.LimitOffset(limitRowcountMark, offset)
+
+	for {
+		i := strings.IndexByte(clause, 0)
+		if i < 0 {
+			buf.WriteString(clause)
+			return
+		}
+
+		mark := clause[i : i+len(limitRowcountMark)]
+		buf.WriteString(clause[:i])
+		buf.Myprintf("%v", exprs[mark])
+		clause = clause[i+len(mark):]
+	}
+}
+
+// dialect returns the dialect of the dbtype of the dao.
+func (p *SQLParsed) dialect() Dialect {
+	if p.opt == nil {
+		return DialectOf("")
+	}
+
+	return DialectOf(p.opt.getDBType())
+}
+
+// splice splices the field parts into the statement, the bind vars of field parts
+// are marked from the index varIndex.
+// The conditions are joined with and (default) or or (part starts with or),
+// and then and-ed to the existing WHERE (part starts with having for HAVING) as a whole,
+// parts like limit ?,? or order by ... replace the related clause.
+func (p *FieldParts) splice(stmt sqlparser.Statement, varIndex int) error {
+	var wheres, havings, tails []string
+
+	for _, f := range p.fieldParts {
+		clause, joiner, part := f.clause()
+		for i := 0; i < f.PartSQLPlTimes; i++ {
+			part = strings.Replace(part, "?", bindVarMark(varIndex), 1)
+			varIndex++
+		}
 
-			p.Vars = append(p.Vars, f.VarMarks()...)
-			p.fp.fieldVars = append(p.fp.fieldVars, f.Vars()...)
+		switch clause {
+		case sqlparser.WhereStr:
+			wheres = appendCondition(wheres, joiner, part)
+		case sqlparser.HavingStr:
+			havings = appendCondition(havings, joiner, part)
+		default:
+			tails = append(tails, part)
 		}
 	}
 
-	if p.opt != nil && p.opt.DBGetter != nil {
-		p.runSQL = convertSQLBindMarks(p.opt.DBGetter.GetDB(), p.runSQL)
+	if err := spliceWhere(stmt, sqlparser.WhereStr, wheres); err != nil {
+		return err
+	}
+
+	if err := spliceWhere(stmt, sqlparser.HavingStr, havings); err != nil {
+		return err
+	}
+
+	for _, tail := range tails {
+		if err := spliceTail(stmt, tail); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func appendCondition(conditions []string, joiner, part string) []string {
+	if len(conditions) == 0 {
+		return append(conditions, part)
+	}
+
+	return append(conditions, joiner, part)
+}
+
+func spliceWhere(stmt sqlparser.Statement, typ string, conditions []string) error {
+	if len(conditions) == 0 {
+		return nil
+	}
+
+	s := strings.Join(conditions, " ")
+
+	parsed, err := sqlparser.Parse("select 1 from dual where " + s)
+	if err != nil {
+		return fmt.Errorf("parse condition %s error %w", s, err)
+	}
+
+	expr := parsed.(*sqlparser.Select).Where.Expr
+
+	if typ == sqlparser.HavingStr {
+		sel, ok := stmt.(*sqlparser.Select)
+		if !ok {
+			return fmt.Errorf("having %s is only supported for select", s) // nolint:goerr113
+		}
+
+		sel.Having = andWhere(sel.Having, typ, expr)
+
+		return nil
+	}
+
+	w, ok := stmt.(sqlparser.IWhere)
+	if !ok {
+		return fmt.Errorf("where %s is not supported for %T", s, stmt) // nolint:goerr113
+	}
+
+	w.SetWhere(andWhere(w.GetWhere(), typ, expr))
+
+	return nil
+}
+
+// andWhere ands the expr to the where, the OR expressions are wrapped in parentheses.
+func andWhere(where *sqlparser.Where, typ string, expr sqlparser.Expr) *sqlparser.Where {
+	expr = parenOr(expr)
+
+	if where == nil || where.Expr == nil {
+		return sqlparser.NewWhere(typ, expr)
+	}
+
+	return sqlparser.NewWhere(typ, &sqlparser.AndExpr{Left: parenOr(where.Expr), Right: expr})
+}
+
+func parenOr(expr sqlparser.Expr) sqlparser.Expr {
+	if _, ok := expr.(*sqlparser.OrExpr); ok {
+		return &sqlparser.ParenExpr{Expr: expr}
+	}
+
+	return expr
+}
+
+func spliceTail(stmt sqlparser.Statement, tail string) error {
+	parsed, err := sqlparser.Parse("select 1 from dual " + tail)
+	if err != nil {
+		return fmt.Errorf("parse sql part %s error %w", tail, err)
+	}
+
+	sel := parsed.(*sqlparser.Select)
+	if sel.Where != nil || sel.GroupBy != nil || sel.Having != nil || sel.Lock != "" {
+		return fmt.Errorf("unsupported sql part %s", tail) // nolint:goerr113
+	}
+
+	if len(sel.OrderBy) > 0 {
+		if err := setOrderBy(stmt, sel.OrderBy); err != nil {
+			return err
+		}
+	}
+
+	if sel.Limit != nil {
+		return setLimit(stmt, sel.Limit)
+	}
+
+	return nil
+}
+
+func setLimit(stmt sqlparser.Statement, limit *sqlparser.Limit) error {
+	switch s := stmt.(type) {
+	case *sqlparser.Select:
+		s.Limit = limit
+	case *sqlparser.Union:
+		s.Limit = limit
+	case *sqlparser.Update:
+		s.Limit = limit
+	case *sqlparser.Delete:
+		s.Limit = limit
+	default:
+		return fmt.Errorf("limit is not supported for %T", stmt) // nolint:goerr113
 	}
 
 	return nil
@@ -206,6 +508,31 @@ type FieldPart struct {
 	JoinedSep      bool
 }
 
+// clause tells the clause (where, having or empty for others like limit) the part belongs to,
+// the joiner (and/or) to the previous conditions and the part SQL without the leading keywords.
+func (p FieldPart) clause() (clause, joiner, partSQL string) {
+	clause, joiner, partSQL = sqlparser.WhereStr, "and", strings.TrimSpace(p.PartSQL)
+
+	switch strings.ToLower(FirstWord(partSQL)) {
+	case "limit", "order":
+		return "", "", partSQL
+	case sqlparser.WhereStr, sqlparser.HavingStr:
+		clause = strings.ToLower(FirstWord(partSQL))
+		partSQL = strings.TrimSpace(partSQL[len(clause):])
+	}
+
+	if !p.JoinedSep {
+		return "", "", partSQL
+	}
+
+	if w := strings.ToLower(FirstWord(partSQL)); w == "and" || w == "or" {
+		joiner = w
+		partSQL = strings.TrimSpace(partSQL[len(w):])
+	}
+
+	return clause, joiner, partSQL
+}
+
 func (p FieldPart) VarMarks() []string {
 	vars := make([]string, p.PartSQLPlTimes)
 
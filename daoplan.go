@@ -0,0 +1,146 @@
+package sqlx
+
+import (
+	"database/sql"
+	"reflect"
+	"sync/atomic"
+)
+
+// maxSQLPlans is the max number of the memoized plans of a dao func,
+// the evaluated SQL of the dynamic statements beyond it is parsed on every call.
+const maxSQLPlans = 256
+
+// sqlPlan is the bind plan of the evaluated SQL, which is reused by the calls of the same SQL and driver.
+type sqlPlan struct {
+	runSQL   string
+	vars     []string
+	defaults map[string]interface{}
+	named    bool
+}
+
+// planKey is the key of the plan, the evaluated SQL and the driver type of the db.
+type planKey struct {
+	sql    string
+	driver reflect.Type
+}
+
+// sqlPlans memoizes the plans of the dao func, which is shared by the per-call copies of the SQLParsed.
+type sqlPlans struct {
+	// static is the SQL of the static statement (without if, switch, for and identifiers) evaluated in advance.
+	static   string
+	isStatic bool
+	plans    boundedCache // planKey -> *sqlPlan
+	// checked tells the func in/out is checked, see checkFuncInOut.
+	checked int32
+}
+
+// newSQLPlans creates the plans of the SQL part, the static one is evaluated in advance.
+func newSQLPlans(part SQLPart) *sqlPlans {
+	p := &sqlPlans{plans: boundedCache{max: maxSQLPlans}}
+
+	if isStaticPart(part) {
+		if s, err := part.Eval(map[string]interface{}{}); err == nil {
+			p.static, p.isStatic = s, true
+		}
+	}
+
+	return p
+}
+
+// isStaticPart tells whether the part evaluates to the same SQL in any env.
+func isStaticPart(part SQLPart) bool {
+	switch p := part.(type) {
+	case *LiteralPart:
+		return true
+	case *MultiPart:
+		for _, sub := range p.Parts {
+			if !isStaticPart(sub) {
+				return false
+			}
+		}
+
+		return true
+	case *TrimPart:
+		return isStaticPart(p.Part)
+	case *PostProcessingSQLPart:
+		return p.idents == nil && isStaticPart(p.Part)
+	default:
+		return false
+	}
+}
+
+// isStatic tells whether the SQL is static, whose env is not required to evaluate.
+func (p *SQLParsed) isStatic() bool { return p.plans != nil && p.plans.isStatic }
+
+// evalSQL evaluates the SQL by the env, the static one is evaluated in advance.
+func (p *SQLParsed) evalSQL(env map[string]interface{}) (string, error) {
+	if p.isStatic() {
+		return p.plans.static, nil
+	}
+
+	return p.SQL.Eval(env)
+}
+
+// checkFuncInOutOnce checks the func in/out once for the dao func.
+func (p *SQLParsed) checkFuncInOutOnce(numIn int, f StructField) error {
+	if p.plans != nil && atomic.LoadInt32(&p.plans.checked) == 1 {
+		return nil
+	}
+
+	if err := p.checkFuncInOut(numIn, f); err != nil {
+		return err
+	}
+
+	if p.plans != nil {
+		atomic.StoreInt32(&p.plans.checked, 1)
+	}
+
+	return nil
+}
+
+// warmPlan precomputes the plan of the static SQL for the db of the dao.
+func (p *SQLParsed) warmPlan() {
+	if !p.isStatic() || p.opt == nil || p.opt.DBGetter == nil {
+		return
+	}
+
+	if db := p.opt.DBGetter.GetDB(); db != nil {
+		p.planOf(db, p.plans.static)
+	}
+}
+
+// usePlan applies the plan of the evaluated SQL for the db, which is memoized.
+func (p *SQLParsed) usePlan(db *sql.DB, runSQL string) {
+	plan := p.planOf(db, runSQL)
+	p.runSQL, p.Vars, p.defaults, p.named = plan.runSQL, plan.vars, plan.defaults, plan.named
+}
+
+// planOf returns the memoized plan of the evaluated SQL for the db, it is made when absent.
+func (p *SQLParsed) planOf(db *sql.DB, runSQL string) *sqlPlan {
+	if p.plans == nil {
+		return p.makePlan(db, runSQL)
+	}
+
+	key := planKey{sql: runSQL, driver: reflect.TypeOf(db.Driver())}
+	if v, ok := p.plans.plans.Load(key); ok {
+		return v.(*sqlPlan)
+	}
+
+	return p.plans.plans.LoadOrStore(key, p.makePlan(db, runSQL)).(*sqlPlan)
+}
+
+// makePlan parses the bind vars of the evaluated SQL, and rewrites the placeholders for the db.
+func (p *SQLParsed) makePlan(db *sql.DB, runSQL string) *sqlPlan {
+	plan := &sqlPlan{runSQL: p.replaceBinds(runSQL, func(int) string { return "?" })}
+	plan.vars, plan.defaults = p.Vars, p.defaults
+
+	if p.opt.namedArgs && p.isBindBy(ByName) {
+		plan.runSQL, plan.named = DialectOfDB(db).Placeholder().rebindNamed(plan.runSQL, plan.vars)
+	}
+
+	if !plan.named {
+		plan.runSQL = convertSQLBindMarks(db, plan.runSQL)
+	}
+
+	return plan
+}
@@ -0,0 +1,113 @@
+package sqlx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// qbeOps defines the supported operators of the query-by-example filter fields.
+// nolint:gochecknoglobals
+var qbeOps = map[string]bool{
+	"=": true, "!=": true, "<>": true, ">": true, ">=": true, "<": true, "<=": true,
+	"like": true, "not like": true, "in": true, "not in": true, "between": true,
+}
+
+// createQBEPart creates the condition for the query-by-example filter field
+// like `col:"age" op:">="`, `col:"name" op:"like"`, `col:"id" op:"in"` or `col:"created" op:"between"`.
+// The op defaults to =, in/not in requires a slice/array value and between requires a slice/array of 2 elements,
+// the empty non-nil slice of in matches nothing, and that of not in matches all.
+// The zero values of non-pointer fields are omitted,
+// the nil pointer fields are omitted, or conditioned by is null with nullable option, like `col:"deleted_at,nullable"`,
+// the non-nil pointer fields are conditioned even they are pointed to zero values, except with omitempty option.
+func (p *SQLParsed) createQBEPart(f reflect.StructField, colTag string, v reflect.Value) error {
+	col, options := parseQBECol(colTag)
+	op := strings.ToLower(strings.Join(strings.Fields(f.Tag.Get("op")), " "))
+
+	if op == "" {
+		op = "="
+	}
+
+	if !qbeOps[op] {
+		return fmt.Errorf("unsupported op %s for field %s", op, f.Name) // nolint:goerr113
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			if options["nullable"] {
+				return p.addQBENull(f, col, op)
+			}
+
+			return nil
+		}
+
+		if v = v.Elem(); options["omitempty"] && v.IsZero() {
+			return nil
+		}
+	} else if v.IsZero() {
+		return nil
+	}
+
+	switch op {
+	case "in", "not in":
+		if k := v.Kind(); k != reflect.Slice && k != reflect.Array {
+			return fmt.Errorf("op %s requires slice for field %s", op, f.Name) // nolint:goerr113
+		}
+
+		if v.Len() == 0 {
+			if op == "in" {
+				p.fp.AddFieldSqlPart("1 = 0", nil, true)
+			}
+
+			return nil
+		}
+
+		marks := strings.TrimSuffix(strings.Repeat("?,", v.Len()), ",")
+		p.fp.AddFieldSqlPart(col+" "+op+" ("+marks+")", sliceValues(v), true)
+	case "between":
+		if k := v.Kind(); k != reflect.Slice && k != reflect.Array || v.Len() != 2 { // nolint:gomnd
+			return fmt.Errorf("op between requires 2 elements for field %s", f.Name) // nolint:goerr113
+		}
+
+		p.fp.AddFieldSqlPart(col+" between ? and ?", sliceValues(v), true)
+	default:
+		p.fp.AddFieldSqlPart(col+" "+op+" ?", []interface{}{v.Interface()}, true)
+	}
+
+	return nil
+}
+
+func (p *SQLParsed) addQBENull(f reflect.StructField, col, op string) error {
+	switch op {
+	case "=":
+		p.fp.AddFieldSqlPart(col+" is null", nil, true)
+	case "!=", "<>":
+		p.fp.AddFieldSqlPart(col+" is not null", nil, true)
+	default:
+		return fmt.Errorf("op %s is not nullable for field %s", op, f.Name) // nolint:goerr113
+	}
+
+	return nil
+}
+
+// parseQBECol parses the col tag like age,omitempty to the column name and its options.
+func parseQBECol(colTag string) (string, map[string]bool) {
+	parts := strings.Split(colTag, ",")
+	options := make(map[string]bool)
+
+	for _, o := range parts[1:] {
+		options[strings.TrimSpace(o)] = true
+	}
+
+	return strings.TrimSpace(parts[0]), options
+}
+
+func sliceValues(v reflect.Value) []interface{} {
+	values := make([]interface{}, v.Len())
+
+	for i := 0; i < v.Len(); i++ {
+		values[i] = v.Index(i).Interface()
+	}
+
+	return values
+}
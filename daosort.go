@@ -0,0 +1,291 @@
+package sqlx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/bingoohuang/sqlparser/sqlparser"
+)
+
+// SortField defines a single field of the dynamic ORDER BY.
+type SortField struct {
+	// Field is the API field name, which should be whitelisted by the sort tag/attr.
+	Field string
+	// Desc tells the direction is descending or not.
+	Desc bool
+}
+
+// Sort defines the dynamic ORDER BY input, like name asc, age desc.
+// The API field names are mapped to the whitelisted column expressions which are declared
+// by the sort tag of the Sort field or the dao func field, or by the sort attr of dotsql item,
+// eg. `sort:"name=u.name,age=u.age,created=u.created_at"`, unknown fields are rejected.
+type Sort []SortField
+
+// nolint:gochecknoglobals
+var SortType = reflect.TypeOf((*Sort)(nil)).Elem()
+
+// ParseSort parses the sort expression like "name,-age" or "name asc, age desc" to Sort.
+func ParseSort(s string) (Sort, error) {
+	sort := make(Sort, 0)
+
+	for _, item := range strings.Split(s, ",") {
+		fields := strings.Fields(item)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if len(fields) > 2 { // nolint:gomnd
+			return nil, fmt.Errorf("bad sort item %s", item) // nolint:goerr113
+		}
+
+		f := SortField{Field: fields[0]}
+
+		if strings.HasPrefix(f.Field, "-") {
+			f.Field, f.Desc = f.Field[1:], true
+		} else if strings.HasPrefix(f.Field, "+") {
+			f.Field = f.Field[1:]
+		}
+
+		if len(fields) == 2 { // nolint:gomnd
+			switch strings.ToLower(fields[1]) {
+			case sqlparser.AscScr:
+			case sqlparser.DescScr:
+				f.Desc = true
+			default:
+				return nil, fmt.Errorf("bad sort direction %s", fields[1]) // nolint:goerr113
+			}
+		}
+
+		if f.Field == "" {
+			return nil, fmt.Errorf("bad sort item %s", item) // nolint:goerr113
+		}
+
+		sort = append(sort, f)
+	}
+
+	return sort, nil
+}
+
+// String returns the sort expression like name asc, age desc.
+func (s Sort) String() string {
+	items := make([]string, len(s))
+
+	for i, f := range s {
+		if f.Desc {
+			items[i] = f.Field + " " + sqlparser.DescScr
+		} else {
+			items[i] = f.Field + " " + sqlparser.AscScr
+		}
+	}
+
+	return strings.Join(items, ", ")
+}
+
+// sortable defines the whitelisted API sort field names to the column expressions.
+type sortable map[string]sqlparser.Expr
+
+// parseSortable parses the whitelist like name=u.name,age=u.age,created=u.created_at.
+// A field without = maps to the column with the same name.
+func parseSortable(s string) (sortable, error) {
+	m := make(sortable)
+
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		field, col := item, item
+		if p := strings.Index(item, "="); p >= 0 {
+			field, col = strings.TrimSpace(item[:p]), strings.TrimSpace(item[p+1:])
+		}
+
+		if field == "" || col == "" {
+			return nil, fmt.Errorf("bad sort whitelist item %s", item) // nolint:goerr113
+		}
+
+		stmt, err := sqlparser.Parse("select 1 from dual order by " + col)
+		if err != nil {
+			return nil, fmt.Errorf("bad sort whitelist column %s error %w", col, err)
+		}
+
+		orderBy := stmt.(*sqlparser.Select).OrderBy
+		if len(orderBy) != 1 {
+			return nil, fmt.Errorf("bad sort whitelist column %s", col) // nolint:goerr113
+		}
+
+		m[field] = orderBy[0].Expr
+	}
+
+	return m, nil
+}
+
+// merge merges the other whitelist to a new one, the other's takes precedence.
+func (s sortable) merge(other sortable) sortable {
+	if len(other) == 0 {
+		return s
+	}
+
+	m := make(sortable, len(s)+len(other))
+
+	for k, v := range s {
+		m[k] = v
+	}
+
+	for k, v := range other {
+		m[k] = v
+	}
+
+	return m
+}
+
+// orderBy maps the sort to ORDER BY clause, unknown fields are rejected.
+func (s sortable) orderBy(sort Sort) (sqlparser.OrderBy, error) {
+	orderBy := make(sqlparser.OrderBy, len(sort))
+
+	for i, f := range sort {
+		expr, ok := s[f.Field]
+		if !ok {
+			return nil, fmt.Errorf("sort field %s is not allowed", f.Field) // nolint:goerr113
+		}
+
+		orderBy[i] = &sqlparser.Order{Expr: expr, Direction: sqlparser.AscScr}
+		if f.Desc {
+			orderBy[i].Direction = sqlparser.DescScr
+		}
+	}
+
+	return orderBy, nil
+}
+
+// prepareSortTags parses the sort tags of the Sort fields of the struct params of the dao func once,
+// the whitelist of each struct type is merged with the sort attribute of the SQL.
+func (p *SQLParsed) prepareSortTags(fnType reflect.Type) error {
+	for i := 0; i < fnType.NumIn(); i++ {
+		t := fnType.In(i)
+		if t.Kind() != reflect.Struct {
+			continue
+		}
+
+		for j := 0; j < t.NumField(); j++ {
+			f := t.Field(j)
+			if f.Type != SortType || f.PkgPath != "" || f.Tag.Get("sort") == "" {
+				continue
+			}
+
+			tagSortable, err := parseSortable(f.Tag.Get("sort"))
+			if err != nil {
+				return fmt.Errorf("failed to parse sort tag of %s.%s error %w", t, f.Name, err)
+			}
+
+			if p.sortTags == nil {
+				p.sortTags = make(map[reflect.Type]sortable)
+			}
+
+			p.sortTags[t] = p.sortable.merge(tagSortable)
+		}
+	}
+
+	return nil
+}
+
+// createSort finds the Sort from the args directly, or from the struct fields/map values of args,
+// more than one Sort is rejected as ambiguous.
+func (p *SQLParsed) createSort(args []reflect.Value) error {
+	var (
+		sort   Sort
+		tagged reflect.Type
+		tag    string
+		found  int
+	)
+
+	for _, arg := range args {
+		if !arg.IsValid() {
+			continue
+		}
+
+		switch t := arg.Type(); {
+		case t == SortType:
+			sort, tagged, tag = arg.Interface().(Sort), nil, ""
+			found++
+		case t.Kind() == reflect.Struct:
+			for i := 0; i < t.NumField(); i++ {
+				if f := t.Field(i); f.Type == SortType && f.PkgPath == "" {
+					sort, tagged, tag = arg.Field(i).Interface().(Sort), t, f.Tag.Get("sort")
+					found++
+				}
+			}
+		case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			for _, k := range arg.MapKeys() {
+				if v, ok := arg.MapIndex(k).Interface().(Sort); ok {
+					sort, tagged, tag = v, nil, ""
+					found++
+				}
+			}
+		}
+	}
+
+	if found > 1 {
+		return fmt.Errorf("ambiguous sort of %s, %d Sort args found", p.ID, found) // nolint:goerr113
+	}
+
+	return p.addSort(sort, tagged, tag)
+}
+
+// addSort sets the ORDER BY of the sort, which is whitelisted by the sort attribute of the SQL,
+// and the sort tag of the struct type tagged, which is parsed here only when the type is not a param of the dao func.
+func (p *SQLParsed) addSort(sort Sort, tagged reflect.Type, tag string) error {
+	if len(sort) == 0 {
+		return nil
+	}
+
+	whitelist := p.sortable
+	if s, ok := p.sortTags[tagged]; ok {
+		whitelist = s
+	} else if tag != "" {
+		tagSortable, err := parseSortable(tag)
+		if err != nil {
+			return err
+		}
+
+		whitelist = whitelist.merge(tagSortable)
+	}
+
+	orderBy, err := whitelist.orderBy(sort)
+	if err != nil {
+		return err
+	}
+
+	p.orderBy = orderBy
+
+	return nil
+}
+
+// setOrderBy replaces the ORDER BY of the statement.
+func setOrderBy(stmt sqlparser.Statement, orderBy sqlparser.OrderBy) error {
+	switch s := stmt.(type) {
+	case *sqlparser.Select:
+		s.OrderBy = orderBy
+	case *sqlparser.Union:
+		s.OrderBy = orderBy
+	case *sqlparser.Update:
+		s.OrderBy = orderBy
+	case *sqlparser.Delete:
+		s.OrderBy = orderBy
+	default:
+		return fmt.Errorf("order by is not supported for %T", stmt) // nolint:goerr113
+	}
+
+	return nil
+}
+
+func sortableOf(part SQLPart) (sortable, error) {
+	if pp, ok := part.(*PostProcessingSQLPart); ok {
+		if s := pp.Attrs["sort"]; s != "" {
+			return parseSortable(s)
+		}
+	}
+
+	return nil, nil
+}
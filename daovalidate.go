@@ -0,0 +1,334 @@
+package sqlx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/bingoohuang/gor"
+	"github.com/bingoohuang/sqlparser/sqlparser"
+)
+
+const (
+	// maxSQLVariants limits the combinations of the dynamic SQL branches to be validated.
+	maxSQLVariants = 64
+	// identPlaceholder replaces the identifiers like ${table} in validating.
+	identPlaceholder = "_sqlx_ident"
+)
+
+// DaoValidationError reports all the problems found in validating the dao.
+type DaoValidationError struct {
+	Problems []error
+}
+
+// Error returns the problems one per line.
+func (e *DaoValidationError) Error() string {
+	lines := make([]string, len(e.Problems))
+	for i, p := range e.Problems {
+		lines[i] = p.Error()
+	}
+
+	return fmt.Sprintf("dao validation found %d problem(s):\n%s", len(e.Problems), strings.Join(lines, "\n"))
+}
+
+// Unwrap returns the problems.
+func (e *DaoValidationError) Unwrap() []error { return e.Problems }
+
+// ValidateDao validates the SQLs of the dao funcs without creating them, all the problems are reported at once
+// by a *DaoValidationError. The dynamic SQLs are validated in the combinations of their branches, that
+// every SQL is parsed by sqlparser, the if/switch/for expressions are compiled against the declared parameter types,
+// the named params should exist on the bound struct, and the result columns should map to the result struct fields.
+func ValidateDao(dao interface{}, createDaoOpts ...CreateDaoOpter) error {
+	v, option, err := createDaoOption(dao, createDaoOpts)
+	if err != nil {
+		return err
+	}
+
+	return validateDao(MakeStructValue(v), option)
+}
+
+func validateDao(structValue *StructValue, option *CreateDaoOpt) error {
+	var problems []error
+
+	for i := 0; i < structValue.NumField; i++ {
+		f := structValue.FieldByIndex(i)
+
+		if f.PkgPath != "" /* not exportable */ || f.Kind != reflect.Func {
+			continue
+		}
+
+		parsed, err := option.parseField(f)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("%s: %w", f.Name, err))
+			continue
+		}
+
+		v := &funcValidator{parsed: parsed, f: f}
+		for _, err := range v.validate() {
+			problems = append(problems, fmt.Errorf("%s: %w", f.Name, err))
+		}
+	}
+
+	if len(problems) > 0 {
+		return &DaoValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// funcValidator validates the SQL of a dao func.
+type funcValidator struct {
+	parsed *SQLParsed
+	f      StructField
+
+	// bean is the struct type of the named params, nil when unknown like a map.
+	bean     reflect.Type
+	scope    exprScope
+	problems []error
+}
+
+func (v *funcValidator) validate() []error {
+	variants, err := v.sqlVariants()
+	if err != nil {
+		return []error{err}
+	}
+
+	for _, s := range variants {
+		v.validateSQL(s)
+	}
+
+	return v.problems
+}
+
+// sqlVariants returns the non-empty SQLs of the func in the combinations of its branches.
+func (v *funcValidator) sqlVariants() ([]string, error) {
+	inTypes := funcInTypes(v.f)
+	if err := v.parsed.checkFuncInOut(len(inTypes), v.f); err != nil {
+		return nil, err
+	}
+
+	variants := make([]string, 0)
+
+	v.scope, v.bean = v.parsed.exprScopeOf(inTypes)
+
+	for _, s := range v.variants(v.parsed.SQL, v.scope.env) {
+		if strings.TrimSpace(s) != "" {
+			variants = append(variants, s)
+		}
+	}
+
+	return variants, nil
+}
+
+func (v *funcValidator) addProblem(err error) { v.problems = append(v.problems, err) }
+
+// compile compiles the expression against the env, and returns the type of its output.
+func (v *funcValidator) compile(e string, env map[string]interface{}) reflect.Type {
+	scope := v.scope
+	scope.env = env
+
+	program, err := scope.compile(e)
+	if err != nil {
+		v.addProblem(fmt.Errorf("bad expression %s error %w", e, err))
+		return nil
+	}
+
+	return program.Node().Type()
+}
+
+// variants returns the SQLs of the part in the combinations of its branches, and compiles its expressions.
+func (v *funcValidator) variants(part SQLPart, env map[string]interface{}) []string {
+	switch p := part.(type) {
+	case *LiteralPart:
+		return []string{p.Literal}
+	case *PostProcessingSQLPart:
+		delimiter := MapValueOrDefault(p.Attrs, "delimiter", ";")
+		raw := p.Part.Raw()
+		for _, loc := range codeIdentRefs(raw) {
+			v.compile(raw[loc[4]:loc[5]], env)
+		}
+
+		// the identifiers are unknown until the calling, validate the SQL with a placeholder identifier.
+		return mapVariants(v.variants(p.Part, env), func(s string) string {
+			return replaceCodeIdentRefs(TrimSQL(s, delimiter), func([]int) string { return identPlaceholder })
+		})
+	case *TrimPart:
+		return mapVariants(v.variants(p.Part, env), p.trim)
+	case *MultiPart:
+		variants := []string{""}
+
+		for _, sub := range p.Parts {
+			variants = joinVariants(variants, v.variants(sub, env))
+		}
+
+		return variants
+	case *IfPart:
+		variants := make([]string, 0)
+
+		for _, c := range p.Conditions {
+			v.compile(c.Expr, env)
+			variants = append(variants, v.variants(c.Part, env)...)
+		}
+
+		return append(variants, v.optionalVariants(p.Else, env)...)
+	case *SwitchPart:
+		v.compile(p.Expr, env)
+
+		variants := make([]string, 0)
+		for _, c := range p.Cases {
+			variants = append(variants, v.variants(c.Part, env)...)
+		}
+
+		return append(variants, v.optionalVariants(p.Default, env)...)
+	case *ForPart:
+		return v.forVariants(p, env)
+	default:
+		return []string{part.Raw()}
+	}
+}
+
+// optionalVariants returns the variants of the else or default part, or an empty SQL when it is absent.
+func (v *funcValidator) optionalVariants(part SQLPart, env map[string]interface{}) []string {
+	if part == nil {
+		return []string{""}
+	}
+
+	return v.variants(part, env)
+}
+
+// forVariants returns the variants of an iteration, the item and index are typed by the items.
+func (v *funcValidator) forVariants(p *ForPart, env map[string]interface{}) []string {
+	loopEnv := p.loopEnv(env, v.compile(p.Items, env))
+
+	// the binds of loop variables are bound to the generated vars, leave them out of the named params checking.
+	return mapVariants(v.variants(p.Part, loopEnv), func(s string) string {
+		return p.bindRe.ReplaceAllLiteralString(s, "?")
+	})
+}
+
+func mapVariants(variants []string, fn func(string) string) []string {
+	for i, s := range variants {
+		variants[i] = fn(s)
+	}
+
+	return variants
+}
+
+// joinVariants joins the variants of the parts in combinations, up to maxSQLVariants.
+func joinVariants(heads, tails []string) []string {
+	joined := make([]string, 0, len(heads)*len(tails))
+
+	for _, h := range heads {
+		for _, t := range tails {
+			if len(joined) == maxSQLVariants {
+				return joined
+			}
+
+			switch {
+			case h == "":
+				joined = append(joined, t)
+			case t == "":
+				joined = append(joined, h)
+			default:
+				joined = append(joined, h+" "+t)
+			}
+		}
+	}
+
+	return joined
+}
+
+// validateSQL parses the SQL, and checks its named params and result columns.
+func (v *funcValidator) validateSQL(s string) {
+	names := make([]string, 0)
+	query := replaceBinds(s, func(b bindVar) string {
+		if !b.HasDefault {
+			names = append(names, b.Name)
+		}
+
+		return "?"
+	})
+
+	stmt, err := sqlparser.Parse(query)
+	if err != nil {
+		v.addProblem(fmt.Errorf("parse sql %s error %w", s, err))
+		return
+	}
+
+	if v.bean != nil {
+		for _, name := range names {
+			if _, err := strconv.Atoi(name); name == "" || err == nil {
+				continue
+			}
+
+			if !hasField(v.bean, name) {
+				v.addProblem(fmt.Errorf("named param :%s is not found in %v", name, v.bean)) // nolint:goerr113
+			}
+		}
+	}
+
+	v.validateColumns(stmt)
+}
+
+// validateColumns checks the select columns map to the fields when the result is a struct.
+func (v *funcValidator) validateColumns(stmt sqlparser.Statement) {
+	numOut := v.f.Type.NumOut()
+	if numOut > 0 && gor.IsError(v.f.Type.Out(numOut-1)) {
+		numOut--
+	}
+
+	if numOut != 1 {
+		return
+	}
+
+	out := v.f.Type.Out(0)
+	if k := out.Kind(); k == reflect.Slice || k == reflect.Ptr {
+		out = out.Elem()
+	}
+
+	if out.Kind() != reflect.Struct {
+		return
+	}
+
+	for union, ok := stmt.(*sqlparser.Union); ok; union, ok = stmt.(*sqlparser.Union) {
+		stmt = union.Left
+	}
+
+	sel, ok := stmt.(*sqlparser.Select)
+	if !ok {
+		return
+	}
+
+	for _, e := range sel.SelectExprs {
+		col := columnNameOf(e)
+		if col != "" && !hasField(out, col) {
+			v.addProblem(fmt.Errorf("column %s is not mapped to any field of %v", col, out)) // nolint:goerr113
+		}
+	}
+}
+
+// columnNameOf returns the alias or the column name of the select expr, empty when unknown like count(*).
+func columnNameOf(e sqlparser.SelectExpr) string {
+	aliased, ok := e.(*sqlparser.AliasedExpr)
+	if !ok {
+		return ""
+	}
+
+	if !aliased.As.IsEmpty() {
+		return aliased.As.String()
+	}
+
+	if c, ok := aliased.Expr.(*sqlparser.ColName); ok {
+		return c.Name.String()
+	}
+
+	return ""
+}
+
+// hasField tells whether the struct has a field matching the column.
+func hasField(structType reflect.Type, col string) bool {
+	_, ok := fieldByColumn(structType, col)
+
+	return ok
+}
@@ -0,0 +1,78 @@
+package sqlx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+const dotSQLStrict = `
+-- name: Find
+select id, age from person
+-- where
+-- if id != ""
+and id = :id
+-- end
+-- if age > 0
+and age = :age
+-- end
+-- end
+order by id;
+
+-- name: FindIn
+select id, age from person where id in (
+-- for p, i in _1 sep ","
+:p.id
+-- end
+);
+
+-- name: BadExpr
+select id from person
+-- if agee > 0
+where age = :age
+-- end
+`
+
+type personStrictDao struct {
+	CreateTable func()          `sql:"create table person(id varchar(100), age int)"`
+	AddAll      func(...person) `sql:"insert into person(id, age) values(:id, :age)"`
+	Find        func(person) []person
+	FindIn      func([]person) []person
+}
+
+type personBadDao struct {
+	Syntax   func() []person `sql:"selec id from person"`
+	BadExpr  func(person) []string
+	BadParam func(person)    `sql:"update person set age = :agee where id = :id"`
+	BadCol   func() []person `sql:"select id, age, addr from person"`
+	Missing  func()
+}
+
+func TestValidateDao(t *testing.T) {
+	that := assert.New(t)
+
+	that.Nil(sqlx.ValidateDao(&personStrictDao{}, sqlx.WithSQLStr(dotSQLStrict)))
+
+	dao := &personStrictDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLStrict), sqlx.WithStrictValidation()))
+
+	dao.CreateTable()
+	dao.AddAll(person{"1", 10}, person{"2", 20}, person{"3", 30})
+	that.Equal([]person{{"2", 20}}, dao.Find(person{Age: 20}))
+	that.Equal([]person{{"1", 10}, {"3", 30}}, dao.FindIn([]person{{ID: "1"}, {ID: "3"}}))
+
+	err := sqlx.CreateDao(&personBadDao{}, sqlx.WithDB(openDB(t)), sqlx.WithSQLStr(dotSQLStrict),
+		sqlx.WithStrictValidation())
+
+	var validationErr *sqlx.DaoValidationError
+
+	that.True(errors.As(err, &validationErr))
+	that.Len(validationErr.Problems, 5)
+	that.Contains(err.Error(), "Syntax: parse sql selec id from person error")
+	that.Contains(err.Error(), "BadExpr: bad expression agee > 0 error")
+	that.Contains(err.Error(), "BadParam: named param :agee is not found in sqlx_test.person")
+	that.Contains(err.Error(), "BadCol: column addr is not mapped to any field of sqlx_test.person")
+	that.Contains(err.Error(), "Missing: failed to find sqlName Missing")
+}
@@ -0,0 +1,427 @@
+package sqlx
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Dialect tells the differences of the SQL of the databases.
+type Dialect interface {
+	// Name returns the name of the dialect, like mysql, sqlite or postgres.
+	Name() string
+	// Placeholder returns the placeholder style of the bind vars.
+	Placeholder() Placeholder
+	// QuoteIdent quotes the identifier, the dotted one like db1.log is quoted part by part.
+	QuoteIdent(ident string) string
+	// LimitOffset returns the limit clause of the row count and the offset, the offset is empty when absent.
+	LimitOffset(limit, offset string) string
+	// Upsert returns the insert statement of the columns which updates the non-key columns
+	// when the keys conflict, the values are bound by name like :name.
+	Upsert(table string, columns, keys []string) string
+	// SchemaSQL returns the schema introspection queries.
+	SchemaSQL() SchemaSQL
+	// ShowCreateTable returns the query of the create statement of the table in its last column,
+	// the error is returned when the dialect cannot show it.
+	ShowCreateTable(table string) (string, error)
+	// IsQuery tells whether the statement of the first keyword like SELECT returns rows.
+	IsQuery(keyword string) bool
+	// ClassifyError classifies the error returned by the driver.
+	ClassifyError(err error) ErrorKind
+}
+
+// SchemaSQL is the schema introspection queries of the dialect.
+type SchemaSQL struct {
+	// Version queries the server version.
+	Version string
+	// Schema queries the name of the current schema (database).
+	Schema string
+	// Tables lists the table names of the current schema in the first column.
+	Tables string
+	// TableInfos lists the tables of the current schema with the columns TABLE_NAME and TABLE_COMMENT.
+	TableInfos string
+	// Columns lists the columns of the tables of the current schema ordered by the table and the position,
+	// with the columns TABLE_NAME, COLUMN_NAME, COLUMN_COMMENT, COLUMN_TYPE, DATA_TYPE, COLUMN_KEY and EXTRA.
+	Columns string
+}
+
+// ErrorKind is the kind of the error returned by the driver.
+type ErrorKind int
+
+const (
+	// ErrorOther is the kind of the unclassified errors.
+	ErrorOther ErrorKind = iota
+	// ErrorDuplicateKey is the kind of the unique or primary key violations.
+	ErrorDuplicateKey
+	// ErrorForeignKey is the kind of the foreign key violations.
+	ErrorForeignKey
+	// ErrorNoTable is the kind of the errors of the absent tables.
+	ErrorNoTable
+	// ErrorDeadlock is the kind of the deadlocks.
+	ErrorDeadlock
+)
+
+// driverError is the error returned by the driver, classified by the dialect of the db.
+type driverError struct {
+	kind ErrorKind
+	err  error
+}
+
+func (e *driverError) Error() string { return e.err.Error() }
+func (e *driverError) Unwrap() error { return e.err }
+
+// classifyError wraps the error returned by the driver of the db with its kind.
+func classifyError(db *sql.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	return &driverError{kind: DialectOfDB(db).ClassifyError(err), err: err}
+}
+
+// ErrorKindOf returns the kind of the driver error returned by the dao funcs,
+// which is classified by the dialect of the db, ErrorOther for the others.
+func ErrorKindOf(err error) ErrorKind {
+	var de *driverError
+	if errors.As(err, &de) {
+		return de.kind
+	}
+
+	return ErrorOther
+}
+
+// nolint:gochecknoglobals
+var (
+	dialectsLock sync.RWMutex
+	dialects     = map[string]Dialect{
+		"mysql":    mysqlDialect{},
+		"sqlite":   sqliteDialect{},
+		"postgres": postgresDialect{},
+	}
+
+	// identQuotes are the quotes of the identifiers of the dbtypes without the dialects.
+	identQuotes = map[string][2]string{
+		"oracle":    {`"`, `"`},
+		"sqlserver": {"[", "]"},
+	}
+
+	pgNoTableRe = regexp.MustCompile(`relation "[^"]+" does not exist`)
+)
+
+// RegisterDialect registers the dialect of the driver name, like the name registered by sql.Register.
+func RegisterDialect(driverName string, d Dialect) {
+	dialectsLock.Lock()
+	defer dialectsLock.Unlock()
+
+	dialects[driverName] = d
+}
+
+// DialectOf returns the dialect of the driver name or its alias like sqlite3 for sqlite,
+// the unknown ones are like MySQL with the placeholder style registered by RegisterPlaceholder.
+func DialectOf(driverName string) Dialect {
+	dialectsLock.RLock()
+	defer dialectsLock.RUnlock()
+
+	if d, ok := dialects[driverName]; ok {
+		return d
+	}
+
+	if d, ok := dialects[dbTypeAliases[driverName]]; ok {
+		return d
+	}
+
+	return genericDialect{name: driverName}
+}
+
+// DialectOfDB returns the dialect of the driver of the db, see LookupDriverName.
+func DialectOfDB(db *sql.DB) Dialect { return DialectOf(LookupDriverName(db.Driver())) }
+
+// quoteParts quotes the parts of the dotted identifier, the close quote in it is doubled.
+func quoteParts(ident, open, close string) string {
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		parts[i] = open + strings.ReplaceAll(part, close, close+close) + close
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// upsertParts returns the quoted table, the columns, the binds and the non-key columns of the upsert.
+func upsertParts(d Dialect, table string, columns, keys []string) (string, string, string, []string) {
+	isKey := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		isKey[k] = true
+	}
+
+	quoted := make([]string, len(columns))
+	binds := make([]string, len(columns))
+	updates := make([]string, 0, len(columns))
+
+	for i, c := range columns {
+		quoted[i], binds[i] = d.QuoteIdent(c), ":"+c
+
+		if !isKey[c] {
+			updates = append(updates, c)
+		}
+	}
+
+	return d.QuoteIdent(table), strings.Join(quoted, ", "), strings.Join(binds, ", "), updates
+}
+
+// conflictUpsert is the upsert of the on conflict syntax of SQLite and Postgres.
+func conflictUpsert(d Dialect, table string, columns, keys []string) string {
+	t, cols, binds, updates := upsertParts(d, table, columns, keys)
+
+	quotedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		quotedKeys[i] = d.QuoteIdent(k)
+	}
+
+	s := "insert into " + t + "(" + cols + ") values(" + binds + ") on conflict(" + strings.Join(quotedKeys, ", ") + ")"
+	if len(updates) == 0 {
+		return s + " do nothing"
+	}
+
+	for i, c := range updates {
+		updates[i] = d.QuoteIdent(c) + " = excluded." + d.QuoteIdent(c)
+	}
+
+	return s + " do update set " + strings.Join(updates, ", ")
+}
+
+// duplicateKeyUpsert is the upsert of the on duplicate key update syntax of MySQL,
+// the first key is updated to itself when all the columns are keys.
+func duplicateKeyUpsert(d Dialect, table string, columns, keys []string) string {
+	t, cols, binds, updates := upsertParts(d, table, columns, keys)
+	if len(updates) == 0 && len(keys) > 0 {
+		updates = append(updates, keys[0])
+	}
+
+	for i, c := range updates {
+		updates[i] = d.QuoteIdent(c) + " = values(" + d.QuoteIdent(c) + ")"
+	}
+
+	return "insert into " + t + "(" + cols + ") values(" + binds + ") on duplicate key update " + strings.Join(updates, ", ")
+}
+
+func isKeyword(keyword string, keywords ...string) bool {
+	for _, k := range keywords {
+		if strings.EqualFold(keyword, k) {
+			return true
+		}
+	}
+
+	return false
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) Name() string                   { return "mysql" }
+func (mysqlDialect) Placeholder() Placeholder       { return PlaceholderQuestion }
+func (mysqlDialect) QuoteIdent(ident string) string { return quoteParts(ident, "`", "`") }
+
+func (mysqlDialect) LimitOffset(limit, offset string) string {
+	if offset == "" {
+		return "limit " + limit
+	}
+
+	return "limit " + offset + ", " + limit
+}
+
+func (d mysqlDialect) Upsert(table string, columns, keys []string) string {
+	return duplicateKeyUpsert(d, table, columns, keys)
+}
+
+func (mysqlDialect) SchemaSQL() SchemaSQL {
+	return SchemaSQL{
+		Version: "SELECT version()",
+		Schema:  "select database()",
+		Tables:  "SHOW TABLES",
+		TableInfos: "select TABLE_NAME, TABLE_COMMENT from information_schema.TABLES " +
+			"where TABLE_SCHEMA = database()",
+		Columns: "select TABLE_NAME, COLUMN_NAME, COLUMN_COMMENT, COLUMN_TYPE, DATA_TYPE, COLUMN_KEY, EXTRA " +
+			"from information_schema.COLUMNS where TABLE_SCHEMA = database() order by TABLE_NAME, ORDINAL_POSITION",
+	}
+}
+
+func (mysqlDialect) ShowCreateTable(table string) (string, error) {
+	return "SHOW CREATE TABLE " + table, nil
+}
+
+func (mysqlDialect) IsQuery(keyword string) bool {
+	return isKeyword(keyword, "SELECT", "SHOW", "DESC", "DESCRIBE", "EXPLAIN")
+}
+
+func (mysqlDialect) ClassifyError(err error) ErrorKind {
+	var me *mysql.MySQLError
+	if !errors.As(err, &me) {
+		return ErrorOther
+	}
+
+	switch me.Number {
+	case 1062: // nolint:gomnd
+		return ErrorDuplicateKey
+	case 1216, 1217, 1451, 1452: // nolint:gomnd
+		return ErrorForeignKey
+	case 1146: // nolint:gomnd
+		return ErrorNoTable
+	case 1213: // nolint:gomnd
+		return ErrorDeadlock
+	default:
+		return ErrorOther
+	}
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string                   { return "sqlite" }
+func (sqliteDialect) Placeholder() Placeholder       { return PlaceholderQuestion }
+func (sqliteDialect) QuoteIdent(ident string) string { return quoteParts(ident, `"`, `"`) }
+
+func (sqliteDialect) LimitOffset(limit, offset string) string { return limitOffset(limit, offset) }
+
+func (d sqliteDialect) Upsert(table string, columns, keys []string) string {
+	return conflictUpsert(d, table, columns, keys)
+}
+
+func (sqliteDialect) SchemaSQL() SchemaSQL {
+	return SchemaSQL{
+		Version: "select sqlite_version()",
+		Schema:  "select 'main'",
+		Tables:  "select name from sqlite_master where type = 'table' and name not like 'sqlite_%' order by name",
+		TableInfos: "select name as TABLE_NAME, '' as TABLE_COMMENT from sqlite_master " +
+			"where type = 'table' and name not like 'sqlite_%' order by name",
+		Columns: "select m.name as TABLE_NAME, c.name as COLUMN_NAME, '' as COLUMN_COMMENT, " +
+			"lower(c.type) as COLUMN_TYPE, lower(c.type) as DATA_TYPE, " +
+			"case when c.pk > 0 then 'PRI' else '' end as COLUMN_KEY, '' as EXTRA " +
+			"from sqlite_master m join pragma_table_info(m.name) c " +
+			"where m.type = 'table' and m.name not like 'sqlite_%' order by m.name, c.cid",
+	}
+}
+
+func (sqliteDialect) ShowCreateTable(table string) (string, error) {
+	return "select sql from sqlite_master where type = 'table' and name = '" +
+		strings.ReplaceAll(table, "'", "''") + "'", nil
+}
+
+func (sqliteDialect) IsQuery(keyword string) bool {
+	return isKeyword(keyword, "SELECT", "EXPLAIN", "PRAGMA", "VALUES", "WITH")
+}
+
+func (sqliteDialect) ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorOther
+	}
+
+	switch s := err.Error(); {
+	case strings.Contains(s, "UNIQUE constraint failed"):
+		return ErrorDuplicateKey
+	case strings.Contains(s, "FOREIGN KEY constraint failed"):
+		return ErrorForeignKey
+	case strings.Contains(s, "no such table"):
+		return ErrorNoTable
+	default:
+		return ErrorOther
+	}
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string                   { return "postgres" }
+func (postgresDialect) Placeholder() Placeholder       { return PlaceholderDollar }
+func (postgresDialect) QuoteIdent(ident string) string { return quoteParts(ident, `"`, `"`) }
+
+func (postgresDialect) LimitOffset(limit, offset string) string { return limitOffset(limit, offset) }
+
+func (d postgresDialect) Upsert(table string, columns, keys []string) string {
+	return conflictUpsert(d, table, columns, keys)
+}
+
+func (postgresDialect) SchemaSQL() SchemaSQL {
+	return SchemaSQL{
+		Version: "select version()",
+		Schema:  "select current_schema()",
+		Tables:  "select tablename from pg_tables where schemaname = current_schema() order by tablename",
+		TableInfos: "select t.table_name as TABLE_NAME, " +
+			"coalesce(obj_description(to_regclass(quote_ident(t.table_name)), 'pg_class'), '') as TABLE_COMMENT " +
+			"from information_schema.tables t where t.table_schema = current_schema()",
+		Columns: "select c.table_name as TABLE_NAME, c.column_name as COLUMN_NAME, " +
+			"coalesce(col_description(to_regclass(quote_ident(c.table_name)), c.ordinal_position), '') as COLUMN_COMMENT, " +
+			"c.udt_name as COLUMN_TYPE, c.data_type as DATA_TYPE, " +
+			"case when k.column_name is not null then 'PRI' else '' end as COLUMN_KEY, " +
+			"case when c.column_default like 'nextval(%' then 'auto_increment' else '' end as EXTRA " +
+			"from information_schema.columns c left join (select u.table_name, u.column_name " +
+			"from information_schema.table_constraints t join information_schema.key_column_usage u " +
+			"on t.constraint_name = u.constraint_name and t.table_schema = u.table_schema " +
+			"where t.constraint_type = 'PRIMARY KEY' and t.table_schema = current_schema()) k " +
+			"on c.table_name = k.table_name and c.column_name = k.column_name " +
+			"where c.table_schema = current_schema() order by c.table_name, c.ordinal_position",
+	}
+}
+
+func (postgresDialect) ShowCreateTable(string) (string, error) {
+	return "", errors.New("unsupported dialect postgres to show the create table statement") // nolint:goerr113
+}
+
+func (postgresDialect) IsQuery(keyword string) bool {
+	return isKeyword(keyword, "SELECT", "SHOW", "EXPLAIN", "VALUES", "TABLE", "WITH")
+}
+
+func (postgresDialect) ClassifyError(err error) ErrorKind {
+	if err == nil {
+		return ErrorOther
+	}
+
+	switch s := err.Error(); {
+	case strings.Contains(s, "SQLSTATE 23505"), strings.Contains(s, "duplicate key value violates unique constraint"):
+		return ErrorDuplicateKey
+	case strings.Contains(s, "SQLSTATE 23503"), strings.Contains(s, "violates foreign key constraint"):
+		return ErrorForeignKey
+	case strings.Contains(s, "SQLSTATE 42P01"), pgNoTableRe.MatchString(s):
+		return ErrorNoTable
+	case strings.Contains(s, "SQLSTATE 40P01"), strings.Contains(s, "deadlock detected"):
+		return ErrorDeadlock
+	default:
+		return ErrorOther
+	}
+}
+
+// limitOffset returns the limit clause like limit 10 offset 20.
+func limitOffset(limit, offset string) string {
+	if offset == "" {
+		return "limit " + limit
+	}
+
+	return "limit " + limit + " offset " + offset
+}
+
+// genericDialect is the dialect of the drivers without the registered dialects,
+// which is like MySQL except the placeholder style and the identifier quotes.
+type genericDialect struct {
+	mysqlDialect
+	name string
+}
+
+func (d genericDialect) Name() string             { return d.name }
+func (d genericDialect) Placeholder() Placeholder { return PlaceholderOf(d.name) }
+
+func (d genericDialect) QuoteIdent(ident string) string {
+	dbType := d.name
+	if alias, ok := dbTypeAliases[dbType]; ok {
+		dbType = alias
+	}
+
+	if quotes, ok := identQuotes[dbType]; ok {
+		return quoteParts(ident, quotes[0], quotes[1])
+	}
+
+	return d.mysqlDialect.QuoteIdent(ident)
+}
+
+func (d genericDialect) Upsert(table string, columns, keys []string) string {
+	return duplicateKeyUpsert(d, table, columns, keys)
+}
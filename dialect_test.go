@@ -0,0 +1,117 @@
+package sqlx_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDialectOf(t *testing.T) {
+	that := assert.New(t)
+
+	that.Equal("mysql", sqlx.DialectOf("mysql").Name())
+	that.Equal("sqlite", sqlx.DialectOf("sqlite3").Name())
+	that.Equal("postgres", sqlx.DialectOf("pgx").Name())
+	that.Equal("sqlite3_dollar", sqlx.DialectOf("sqlite3_dollar").Name())
+	that.Equal(sqlx.PlaceholderDollar, sqlx.DialectOf("sqlite3_dollar").Placeholder())
+	that.Equal(sqlx.PlaceholderDollar, sqlx.DialectOf("postgres").Placeholder())
+
+	that.Equal("`db1`.`log`", sqlx.DialectOf("mysql").QuoteIdent("db1.log"))
+	that.Equal(`"a""b"`, sqlx.DialectOf("postgres").QuoteIdent(`a"b`))
+	that.Equal("[log]", sqlx.DialectOf("mssql").QuoteIdent("log"))
+	that.Equal("`log`", sqlx.DialectOf("unknown").QuoteIdent("log"))
+
+	that.Equal("limit 20, 10", sqlx.DialectOf("mysql").LimitOffset("10", "20"))
+	that.Equal("limit 10 offset 20", sqlx.DialectOf("sqlite3").LimitOffset("10", "20"))
+	that.Equal("limit 10", sqlx.DialectOf("postgres").LimitOffset("10", ""))
+
+	that.Equal("insert into `person`(`id`, `age`) values(:id, :age) on duplicate key update `age` = values(`age`)",
+		sqlx.DialectOf("mysql").Upsert("person", []string{"id", "age"}, []string{"id"}))
+	that.Equal(`insert into "person"("id") values(:id) on conflict("id") do nothing`,
+		sqlx.DialectOf("postgres").Upsert("person", []string{"id"}, []string{"id"}))
+
+	_, err := sqlx.DialectOf("postgres").ShowCreateTable("person")
+	that.EqualError(err, "unsupported dialect postgres to show the create table statement")
+	that.True(sqlx.NewMySQLMore("mysql").Matches())
+	that.False(sqlx.NewMySQLMore("sqlite3").Matches())
+
+	that.True(sqlx.DialectOf("postgres").IsQuery("with"))
+	that.False(sqlx.DialectOf("mysql").IsQuery("with"))
+
+	_, isQuery := sqlx.IsQuerySQL("with t as (select 1) select * from t")
+	that.True(isQuery)
+	_, isQuery = sqlx.IsQuerySQL("pragma table_info(person)")
+	that.True(isQuery)
+	_, isQuery = sqlx.IsQuerySQL("insert into person(id) values(1)")
+	that.False(isQuery)
+
+	that.Equal(sqlx.ErrorDuplicateKey, sqlx.DialectOf("mysql").ClassifyError(&mysql.MySQLError{Number: 1062}))
+	that.Equal(sqlx.ErrorNoTable, sqlx.DialectOf("postgres").ClassifyError(pgError(`pq: relation "person" does not exist`)))
+	that.Equal(sqlx.ErrorOther, sqlx.DialectOf("postgres").ClassifyError(pgError(`pq: column "name" does not exist`)))
+}
+
+type pgError string
+
+func (e pgError) Error() string { return string(e) }
+
+type dialectDao struct {
+	CreateTable func()             `sql:"create table person(id varchar(100) primary key, age int)"`
+	Upsert      func(person) error `upsert:"person"`
+	Insert      func(person) error `sql:"insert into person(id, age) values(:id, :age)"`
+	ListAll     func() []person    `sql:"select id, age from person order by id"`
+	ListNone    func() error       `sql:"select id from no_such_table"`
+}
+
+type dialectColumn struct {
+	TableName  string `name:"TABLE_NAME"`
+	ColumnName string `name:"COLUMN_NAME"`
+	DataType   string `name:"DATA_TYPE"`
+	ColumnKey  string `name:"COLUMN_KEY"`
+}
+
+type dialectSchemaDao struct {
+	Tables  func() []string
+	Columns func() []dialectColumn
+}
+
+func TestDialectSQLite(t *testing.T) {
+	that := assert.New(t)
+
+	db := openDB(t)
+	d := sqlx.DialectOfDB(db)
+	that.Equal("sqlite", d.Name())
+
+	dao := &dialectDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db)))
+
+	dao.CreateTable()
+	that.Nil(dao.Upsert(person{"1", 10}))
+	that.Nil(dao.Upsert(person{"1", 20}))
+	that.Equal([]person{{"1", 20}}, dao.ListAll())
+
+	that.Equal(sqlx.ErrorNoTable, d.ClassifyError(dao.ListNone()))
+	that.Equal(sqlx.ErrorOther, d.ClassifyError(nil))
+
+	s := d.SchemaSQL()
+	schemaDao := &dialectSchemaDao{}
+	that.Nil(sqlx.CreateDao(schemaDao, sqlx.WithDB(db),
+		sqlx.WithSQLStr("-- name: Tables\n"+s.Tables+"\n\n-- name: Columns\n"+s.Columns)))
+
+	that.Equal([]string{"person"}, schemaDao.Tables())
+	that.Equal([]dialectColumn{
+		{TableName: "person", ColumnName: "id", DataType: "varchar(100)", ColumnKey: "PRI"},
+		{TableName: "person", ColumnName: "age", DataType: "int"},
+	}, schemaDao.Columns())
+
+	that.Equal(sqlx.ErrorDuplicateKey, d.ClassifyError(dao.Insert(person{"1", 30})))
+	that.Equal(sqlx.ErrorDuplicateKey, sqlx.ErrorKindOf(dao.Insert(person{"1", 30})))
+	that.Equal(sqlx.ErrorNoTable, sqlx.ErrorKindOf(dao.ListNone()))
+	that.Equal(sqlx.ErrorOther, sqlx.ErrorKindOf(errors.New("not from the driver")))
+
+	createSQL, err := (&sqlx.MySQLDumper{Sdb: db}).CreateTableSQL("person")
+	that.Nil(err)
+	that.Equal("CREATE TABLE person(id varchar(100) primary key, age int)", createSQL)
+}
@@ -3,14 +3,20 @@ package sqlx
 import (
 	"bufio"
 	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
+	"path"
+	"path/filepath"
+	"reflect"
 	"regexp"
 	"strings"
 	"unicode"
 
 	"github.com/expr-lang/expr"
+	exprparser "github.com/expr-lang/expr/parser"
 	"github.com/expr-lang/expr/vm"
 	funk "github.com/thoas/go-funk"
 )
@@ -28,6 +34,24 @@ type DotSQLItem struct {
 	Content []string
 	Name    string
 	Attrs   map[string]string
+
+	// File is the file name where the item is defined, empty when not loaded from a file.
+	File string
+	// Line is the line number of the -- name: or -- fragment: tag.
+	Line int
+	// Lines are the line numbers of the Content.
+	Lines []int
+
+	// Params are the parameters declared by the -- param: lines, like -- param: id int64.
+	Params []DotSQLParam
+	// Result is the result type declared by the -- result: line, like -- result: []User.
+	Result string
+}
+
+// DotSQLParam is the parameter declared by the -- param: name type line.
+type DotSQLParam struct {
+	Name string
+	Type string
 }
 
 var re = regexp.MustCompile(`\s*(\w+)\s*(:\s*(\S+))?`)
@@ -52,21 +76,44 @@ func ParseDotTag(line, prefix, mainTag string) (map[string]string, string) {
 
 // DotSQLScanner scans the SQL statements from .sql files.
 type DotSQLScanner struct {
-	line    string
-	queries map[string]DotSQLItem
-	current DotSQLItem
+	// File is the file name to be scanned, used in the error messages.
+	File string
+	// Fragments are the fragments defined by -- fragment: tags, which are scanned by Run.
+	Fragments map[string]DotSQLItem
+	// Err is the first error found by Run, like the duplicate names in the file.
+	Err error
+
+	line     string
+	lineNo   int
+	queries  map[string]DotSQLItem
+	current  DotSQLItem
+	fragment bool
 }
 
-func (s *DotSQLScanner) createNewItem(name string, tag map[string]string) {
-	s.current = DotSQLItem{Name: name, Attrs: tag, Content: make([]string, 0)}
+func (s *DotSQLScanner) createNewItem(name string, tag map[string]string, fragment bool) {
+	s.current = DotSQLItem{Name: name, Attrs: tag, Content: make([]string, 0), File: s.File, Line: s.lineNo}
+	s.fragment = fragment
 }
 
 type stateFn func() stateFn
 
-func (s *DotSQLScanner) initialState() stateFn {
+// scanTag scans the -- name: or -- fragment: tag to create a new item.
+func (s *DotSQLScanner) scanTag() bool {
 	if tag, name := ParseDotTag(s.line, "--", "name"); name != "" {
-		s.createNewItem(name, tag)
+		s.createNewItem(name, tag, false)
+		return true
+	}
 
+	if tag, name := ParseDotTag(s.line, "--", "fragment"); name != "" {
+		s.createNewItem(name, tag, true)
+		return true
+	}
+
+	return false
+}
+
+func (s *DotSQLScanner) initialState() stateFn {
+	if s.scanTag() {
 		return s.queryState
 	}
 
@@ -74,15 +121,37 @@ func (s *DotSQLScanner) initialState() stateFn {
 }
 
 func (s *DotSQLScanner) queryState() stateFn {
-	if tag, name := ParseDotTag(s.line, "--", "name"); name != "" {
-		s.createNewItem(name, tag)
-	} else {
+	if !s.scanTag() && !s.scanDeclaration() {
 		s.appendQueryLine()
 	}
 
 	return s.queryState
 }
 
+// scanDeclaration scans the -- param: name type and -- result: type lines in the header of the current item,
+// which is before the first SQL line, the ones after are left as comments.
+func (s *DotSQLScanner) scanDeclaration() bool {
+	l := strings.TrimSpace(s.line)
+	if len(s.current.Content) > 0 || !strings.HasPrefix(l, "--") {
+		return false
+	}
+
+	l = strings.TrimSpace(l[2:])
+
+	if v := strings.TrimPrefix(l, "param:"); v != l {
+		if fields := strings.Fields(v); len(fields) > 0 {
+			param := DotSQLParam{Name: fields[0], Type: strings.Join(fields[1:], " ")}
+			s.current.Params = append(s.current.Params, param)
+		}
+	} else if v := strings.TrimPrefix(l, "result:"); v != l {
+		s.current.Result = strings.TrimSpace(v)
+	} else {
+		return false
+	}
+
+	return true
+}
+
 func (s *DotSQLScanner) appendQueryLine() {
 	line := strings.Trim(s.line, " \t")
 	if len(line) == 0 {
@@ -90,15 +159,37 @@ func (s *DotSQLScanner) appendQueryLine() {
 	}
 
 	s.current.Content = append(s.current.Content, strings.TrimSpace(line))
-	s.queries[s.current.Name] = s.current
+	s.current.Lines = append(s.current.Lines, s.lineNo)
+	s.save()
+}
+
+// save saves the current item to the queries or the fragments, the first one of the duplicate names is kept.
+func (s *DotSQLScanner) save() {
+	items, kind, name := s.queries, "name", s.current.variantName()
+	if s.fragment {
+		items, kind, name = s.Fragments, "fragment", s.current.Name
+	}
+
+	if dup, ok := items[name]; ok && dup.Line != s.current.Line {
+		if s.Err == nil {
+			s.Err = fmt.Errorf("dotsql: duplicate %s %s at %s, first defined at %s", // nolint:goerr113
+				kind, name, position(s.File, s.current.Line), position(dup.File, dup.Line))
+		}
+
+		return
+	}
+
+	items[name] = s.current
 }
 
 // Run runs the scanner.
 func (s *DotSQLScanner) Run(io *bufio.Scanner) map[string]DotSQLItem {
 	s.queries = make(map[string]DotSQLItem)
+	s.Fragments = make(map[string]DotSQLItem)
 
 	for state := s.initialState; io.Scan(); {
 		s.line = io.Text()
+		s.lineNo++
 		state = state()
 	}
 
@@ -106,28 +197,99 @@ func (s *DotSQLScanner) Run(io *bufio.Scanner) map[string]DotSQLItem {
 }
 
 // DotSQL is the set of SQL statements.
+// The statements loaded from files are also named by the file names as namespaces, like user.FindByID for user.sql,
+// and the names should be unique across the files, the duplicate ones fail the loading.
 type DotSQL struct {
 	Sqls map[string]DotSQLItem
+	// Fragments are the reusable fragments which are included by -- include: directives.
+	Fragments map[string]DotSQLItem
+
+	exprOpts []DotSQLOption
+}
+
+// nolint:gochecknoglobals
+var dbTypeAliases = map[string]string{
+	"sqlite3":    "sqlite",
+	"pgx":        "postgres",
+	"postgresql": "postgres",
+	"mssql":      "sqlserver",
+	"godror":     "oracle",
+	"oci8":       "oracle",
 }
 
 // Raw returns the query, everything after the --name tag.
+// The name can be suffixed by @dbtype, like ListAll@sqlite3, to select the variant tagged by
+// dbtype: sqlite3 or its alias dbtype: sqlite, and the untagged one is the fallback.
 func (d DotSQL) Raw(name string) (SQLPart, error) {
+	if p := strings.LastIndex(name, "@"); p >= 0 {
+		if v, ok, err := d.variant(name[:p], name[p+1:]); ok {
+			return v, err
+		}
+
+		name = name[:p]
+	}
+
 	v, err := d.lookupQuery(name)
 
 	return v, err
 }
 
+// variant returns the query of the name tagged by the dbtype or its alias, false when there is no such variant.
+func (d DotSQL) variant(name, dbType string) (SQLPart, bool, error) {
+	for _, t := range []string{dbType, dbTypeAliases[dbType]} {
+		if variant := name + "@" + t; t != "" && d.has(variant) {
+			v, err := d.lookupQuery(variant)
+			return v, true, err
+		}
+	}
+
+	return nil, false, nil
+}
+
+// has tells whether the name is defined.
+func (d DotSQL) has(name string) bool {
+	_, ok := d.Sqls[name]
+	return ok
+}
+
 func (d DotSQL) lookupQuery(name string) (query SQLPart, err error) {
 	s, ok := d.Sqls[name]
 	if !ok {
 		return nil, fmt.Errorf("dotsql: '%s' could not be found", name) // nolint:goerr113
 	}
 
-	query, err = s.DynamicSQL()
+	query, err = s.dynamicSQL(d.exprOpts)
 
 	return query, err
 }
 
+// variantName returns the name of the item, suffixed by @dbtype when it is tagged by dbtype.
+func (d DotSQLItem) variantName() string {
+	if dbType := d.Attrs["dbtype"]; dbType != "" {
+		return d.Name + "@" + dbType
+	}
+
+	return d.Name
+}
+
+// variantNames returns the names of the item, like ListAll@mysql and ListAll@sqlite for dbtype: mysql,sqlite.
+func (d DotSQLItem) variantNames() []string {
+	dbType := d.Attrs["dbtype"]
+	if dbType == "" {
+		return []string{d.Name}
+	}
+
+	names := make([]string, 0)
+
+	for _, t := range strings.Split(dbType, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			names = append(names, d.Name+"@"+t)
+		}
+	}
+
+	return names
+}
+
 // RawSQL returns the raw SQL.
 func (d DotSQLItem) RawSQL() string {
 	delimiter := d.Attrs["delimiter"]
@@ -152,44 +314,244 @@ func TrimSQL(s, delimiter string) string {
 }
 
 // DynamicSQL returns the dynamic SQL.
-func (d DotSQLItem) DynamicSQL() (SQLPart, error) {
-	lines := ConvertSQLLines(d.Content)
+func (d DotSQLItem) DynamicSQL() (SQLPart, error) { return d.dynamicSQL(nil) }
+
+// dynamicSQL parses the dynamic SQL, whose expressions are compiled with the options.
+func (d DotSQLItem) dynamicSQL(opts []DotSQLOption) (SQLPart, error) {
+	lines, index := convertSQLLines(d.Content)
+	src := &dotSQLSource{item: d, index: index}
 
 	_, part, err := ParseDynamicSQL(lines)
 	if err != nil {
-		return nil, err
+		return nil, src.errorAt(err)
+	}
+
+	p := &PostProcessingSQLPart{
+		Part:     part,
+		Attrs:    d.Attrs,
+		exprOpts: opts,
+		src:      src,
 	}
 
-	if err := part.Compile(); err != nil {
+	if err := p.Compile(); err != nil {
 		return nil, err
 	}
 
-	return &PostProcessingSQLPart{
-		Part:  part,
-		Attrs: d.Attrs,
-	}, nil
+	return p, nil
 }
 
 // DotSQLLoad imports sql queries from any io.Reader.
 func DotSQLLoad(r io.Reader) (*DotSQL, error) {
-	return &DotSQL{(&DotSQLScanner{}).Run(bufio.NewScanner(r))}, nil
+	d := &DotSQL{}
+	if err := d.scan(r, ""); err != nil {
+		return nil, err
+	}
+
+	if err := d.resolveIncludes(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
 }
 
 // DotSQLLoadFile imports SQL queries from the file.
 func DotSQLLoadFile(sqlFile string) (*DotSQL, error) {
+	return DotSQLLoadFiles(sqlFile)
+}
+
+// DotSQLLoadFiles imports SQL queries from the files, the fragments can be included across the files.
+func DotSQLLoadFiles(sqlFiles ...string) (*DotSQL, error) {
+	d := &DotSQL{}
+
+	for _, sqlFile := range sqlFiles {
+		if err := d.scanFile(sqlFile); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.resolveIncludes(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *DotSQL) scanFile(sqlFile string) error {
 	f, err := os.Open(sqlFile)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
 	defer f.Close()
 
-	return DotSQLLoad(f)
+	return d.scan(f, sqlFile)
+}
+
+// scan scans the items and fragments from the reader into the DotSQL.
+func (d *DotSQL) scan(r io.Reader, file string) error {
+	if d.Sqls == nil {
+		d.Sqls = make(map[string]DotSQLItem)
+		d.Fragments = make(map[string]DotSQLItem)
+	}
+
+	s := &DotSQLScanner{File: file}
+	ns := namespaceOf(file)
+
+	items := s.Run(bufio.NewScanner(r))
+	if s.Err != nil {
+		return s.Err
+	}
+
+	for _, item := range items {
+		for _, name := range item.variantNames() {
+			if err := d.addItem(ns, name, item); err != nil {
+				return err
+			}
+		}
+	}
+
+	for name, fragment := range s.Fragments {
+		if dup, ok := d.Fragments[name]; ok {
+			return fmt.Errorf("dotsql: duplicate fragment %s at %s, first defined at %s", // nolint:goerr113
+				name, position(fragment.File, fragment.Line), position(dup.File, dup.Line))
+		}
+
+		d.Fragments[name] = fragment
+	}
+
+	return nil
 }
 
 // DotSQLLoadString imports SQL queries from the string.
 func DotSQLLoadString(s string) (*DotSQL, error) { return DotSQLLoad(bytes.NewBufferString(s)) }
 
+// addItem adds the item by its name, and by the name qualified by the namespace ns if ns is not empty,
+// the names already defined, like the same name in another file, are reported as duplicate.
+func (d *DotSQL) addItem(ns, name string, item DotSQLItem) error {
+	names := []string{name}
+	if ns != "" {
+		names = []string{ns + "." + name, name}
+	}
+
+	for _, n := range names {
+		if dup, ok := d.Sqls[n]; ok {
+			return fmt.Errorf("dotsql: duplicate name %s at %s, first defined at %s", // nolint:goerr113
+				n, position(item.File, item.Line), position(dup.File, dup.Line))
+		}
+	}
+
+	for _, n := range names {
+		d.Sqls[n] = item
+	}
+
+	return nil
+}
+
+// namespaceOf returns the namespace of the file, which is the base name without extension, like user of sql/user.sql.
+func namespaceOf(file string) string {
+	if file == "" {
+		return ""
+	}
+
+	base := path.Base(filepath.ToSlash(file))
+
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// DotSQLLoadFS imports SQL queries from the files of fsys matched by the patterns, like sql/*.sql of embed.FS,
+// the .sql files are imported recursively when a directory is matched, and all the .sql files when no patterns.
+func DotSQLLoadFS(fsys fs.FS, patterns ...string) (*DotSQL, error) {
+	files, err := globSQLFiles(fsys, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &DotSQL{}
+
+	for _, f := range files {
+		if err := d.scanFS(fsys, f); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := d.resolveIncludes(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// globSQLFiles returns the .sql files of fsys matched by the patterns.
+func globSQLFiles(fsys fs.FS, patterns []string) ([]string, error) {
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+
+	files := make([]string, 0)
+	seen := make(map[string]bool)
+
+	for _, pattern := range patterns {
+		matches, err := fs.Glob(fsys, pattern)
+		if err != nil {
+			return nil, fmt.Errorf("dotsql: bad pattern %s error %w", pattern, err)
+		}
+
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("dotsql: no files matched %s", pattern) // nolint:goerr113
+		}
+
+		for _, match := range matches {
+			matched, err := sqlFilesOf(fsys, match)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, f := range matched {
+				if !seen[f] {
+					seen[f] = true
+					files = append(files, f)
+				}
+			}
+		}
+	}
+
+	return files, nil
+}
+
+// sqlFilesOf returns the file itself, or the .sql files under the directory.
+func sqlFilesOf(fsys fs.FS, name string) ([]string, error) {
+	info, err := fs.Stat(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		return []string{name}, nil
+	}
+
+	files := make([]string, 0)
+	err = fs.WalkDir(fsys, name, func(p string, e fs.DirEntry, err error) error {
+		if err == nil && !e.IsDir() && strings.EqualFold(path.Ext(p), ".sql") {
+			files = append(files, p)
+		}
+
+		return err
+	})
+
+	return files, err
+}
+
+func (d *DotSQL) scanFS(fsys fs.FS, name string) error {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return d.scan(f, name)
+}
+
 // SQLPart defines the dynamic SQL part.
 type SQLPart interface {
 	// Compile compile the condition int advance.
@@ -204,18 +566,38 @@ type SQLPart interface {
 type PostProcessingSQLPart struct {
 	Part  SQLPart
 	Attrs map[string]string
+
+	exprOpts []DotSQLOption
+	idents   *identInterpolator
+	src      *dotSQLSource
 }
 
 // Compile compile the condition int advance.
-func (p *PostProcessingSQLPart) Compile() error {
-	return p.Part.Compile()
+func (p *PostProcessingSQLPart) Compile() error { return p.compileExpr(exprScope{}) }
+
+func (p *PostProcessingSQLPart) compileExpr(s exprScope) (err error) {
+	s = s.withOptions(p.exprOpts)
+	if err = compilePart(p.Part, s); err != nil {
+		return p.src.errorAt(err)
+	}
+
+	p.idents, err = compileIdents(p.Part.Raw(), p.Attrs, s)
+
+	return p.src.errorAt(err)
 }
 
-// Eval evaluated the dynamic sql with env.
+// Eval evaluated the dynamic sql with env,
+// the identifiers like ${table} or #{col} are interpolated, see identInterpolator.
 func (p *PostProcessingSQLPart) Eval(env map[string]interface{}) (string, error) {
 	eval, err := p.Part.Eval(env)
 	if err != nil {
-		return "", err
+		return "", p.src.errorAt(err)
+	}
+
+	if p.idents != nil {
+		if eval, err = p.idents.interpolate(eval, env); err != nil {
+			return "", p.src.errorAt(err)
+		}
 	}
 
 	delimiter := MapValueOrDefault(p.Attrs, "delimiter", ";")
@@ -265,15 +647,25 @@ type IfPart struct {
 }
 
 // Compile compile the condition int advance.
-func (p *IfPart) Compile() (err error) {
+func (p *IfPart) Compile() error { return p.compileExpr(exprScope{}) }
+
+func (p *IfPart) compileExpr(s exprScope) (err error) {
 	for i, c := range p.Conditions {
-		if c.CompiledExpr, err = expr.Compile(c.Expr); err != nil {
+		if c.CompiledExpr, err = s.compile(c.Expr); err != nil {
+			return &exprError{expr: c.Expr, err: err}
+		}
+
+		if err := compilePart(c.Part, s); err != nil {
 			return err
 		}
 
 		p.Conditions[i] = c
 	}
 
+	if p.Else != nil {
+		return compilePart(p.Else, s)
+	}
+
 	return nil
 }
 
@@ -300,11 +692,11 @@ func (p *IfPart) Eval(env map[string]interface{}) (string, error) {
 	for _, c := range p.Conditions {
 		output, err := expr.Run(c.CompiledExpr, env)
 		if err != nil {
-			return "", err
+			return "", &exprError{expr: c.Expr, err: err}
 		}
 
 		if yes, ok := output.(bool); !ok {
-			return "", fmt.Errorf("%s is not a bool expression", c.Expr) // nolint:goerr113
+			return "", &exprError{expr: c.Expr, err: fmt.Errorf("%s is not a bool expression", c.Expr)} // nolint:goerr113
 		} else if yes {
 			return c.Part.Eval(env)
 		}
@@ -332,6 +724,105 @@ func (p *IfPart) Raw() string {
 	return raw
 }
 
+// SwitchCase defines a case of SwitchPart, matched when the switch value equals one of the case values.
+type SwitchCase struct {
+	Values         string
+	CompiledValues []interface{}
+	Part           SQLPart
+}
+
+// SwitchPart is the part that has the format of switch ... case ... default ... end.
+type SwitchPart struct {
+	Expr         string
+	CompiledExpr *vm.Program
+	Cases        []SwitchCase
+	Default      SQLPart
+}
+
+// Compile compile the condition int advance.
+func (p *SwitchPart) Compile() error { return p.compileExpr(exprScope{}) }
+
+func (p *SwitchPart) compileExpr(s exprScope) (err error) {
+	if p.CompiledExpr, err = s.compile(p.Expr); err != nil {
+		return &exprError{expr: p.Expr, err: err}
+	}
+
+	for i, c := range p.Cases {
+		// the case values are constants like 'name', 'age' or 1, 2, evaluated once as an array.
+		values, err := expr.Eval("["+c.Values+"]", nil)
+		if err != nil {
+			return &exprError{expr: c.Values, err: fmt.Errorf("bad case %s error %w", c.Values, err)}
+		}
+
+		c.CompiledValues = values.([]interface{})
+
+		if err := compilePart(c.Part, s); err != nil {
+			return err
+		}
+
+		p.Cases[i] = c
+	}
+
+	if p.Default != nil {
+		return compilePart(p.Default, s)
+	}
+
+	return nil
+}
+
+// Eval evaluates the SQL part to a real SQL.
+func (p *SwitchPart) Eval(env map[string]interface{}) (string, error) {
+	output, err := expr.Run(p.CompiledExpr, env)
+	if err != nil {
+		return "", &exprError{expr: p.Expr, err: err}
+	}
+
+	for _, c := range p.Cases {
+		for _, v := range c.CompiledValues {
+			if switchEquals(output, v) {
+				return c.Part.Eval(env)
+			}
+		}
+	}
+
+	if p.Default != nil {
+		return p.Default.Eval(env)
+	}
+
+	return "", nil
+}
+
+// switchEquals tells whether the switch value equals the case value,
+// the numbers are compared by their values, and so are the strings of different string types.
+func switchEquals(a, b interface{}) bool {
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+
+	if fa, fb, ok := numberValues(va, vb); ok {
+		return fa == fb
+	}
+
+	if va.Kind() == reflect.String && vb.Kind() == reflect.String {
+		return va.String() == vb.String()
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+// Raw returns the raw content.
+func (p *SwitchPart) Raw() string {
+	raw := p.Expr
+
+	for _, c := range p.Cases {
+		raw += "\n" + c.Part.Raw()
+	}
+
+	if p.Default != nil {
+		raw += "\n" + p.Default.Raw()
+	}
+
+	return raw
+}
+
 // MultiPart is the multi SQLParts.
 type MultiPart struct {
 	Parts []SQLPart
@@ -383,9 +874,11 @@ func (p *MultiPart) AddPart(part SQLPart) {
 }
 
 // Compile compile the condition int advance.
-func (p *MultiPart) Compile() error {
+func (p *MultiPart) Compile() error { return p.compileExpr(exprScope{}) }
+
+func (p *MultiPart) compileExpr(s exprScope) error {
 	for _, part := range p.Parts {
-		if err := part.Compile(); err != nil {
+		if err := compilePart(part, s); err != nil {
 			return err
 		}
 	}
@@ -395,6 +888,7 @@ func (p *MultiPart) Compile() error {
 
 var _ SQLPart = (*LiteralPart)(nil)
 var _ SQLPart = (*IfPart)(nil)
+var _ SQLPart = (*SwitchPart)(nil)
 var _ SQLPart = (*MultiPart)(nil)
 var _ SQLPart = (*PostProcessingSQLPart)(nil)
 
@@ -423,8 +917,16 @@ func ParseDynamicSQL(lines []string, terminators ...string) (int, SQLPart, error
 		}
 
 		partLines, part, err := parser.Parse(lines[i+1:])
+		if errors.Is(err, errNotDirective) { // like the -- where without -- end, ignore comment line
+			continue
+		}
+
 		if err != nil {
-			return 0, nil, err
+			if _, ok := err.(*lineError); ok {
+				return 0, nil, shiftLine(err, i+1)
+			}
+
+			return 0, nil, &lineError{index: i, err: err}
 		}
 
 		multiPart.AddPart(part)
@@ -437,21 +939,31 @@ func ParseDynamicSQL(lines []string, terminators ...string) (int, SQLPart, error
 
 // ConvertSQLLines converts the inline comments to line comments
 // and merge the uncomment lines together.
-// nolint:funlen
 func ConvertSQLLines(lines []string) []string {
+	converted, _ := convertSQLLines(lines)
+	return converted
+}
+
+// convertSQLLines converts the lines like ConvertSQLLines,
+// and returns the index of the lines where each converted line starts.
+// nolint:funlen
+func convertSQLLines(lines []string) (converted []string, index []int) {
 	inlineCommentMode := false
 	noneComment := ""
+	noneCommentIndex := 0
 	inlineCommentContent := ""
-	converted := make([]string, 0)
+	inlineCommentIndex := 0
+	converted = make([]string, 0)
+	index = make([]int, 0)
 
-	for _, l := range lines {
+	for i, l := range lines {
 		if strings.HasPrefix(l, "--") {
 			if noneComment != "" {
-				converted = append(converted, noneComment)
+				converted, index = append(converted, noneComment), append(index, noneCommentIndex)
 				noneComment = ""
 			}
 
-			converted = append(converted, l)
+			converted, index = append(converted, l), append(index, i)
 
 			continue
 		}
@@ -466,14 +978,23 @@ func ConvertSQLLines(lines []string) []string {
 		if !inlineCommentMode {
 			inlineCommentStart := strings.Index(l, "/*")
 			if inlineCommentStart < 0 {
+				if noneComment == "" {
+					noneCommentIndex = i
+				}
+
 				noneComment = appendNoneComment(noneComment, l)
 
 				continue
 			}
 
 			inlineCommentMode = true
+			inlineCommentIndex = i
 
 			if before := strings.TrimSpace(l[0:inlineCommentStart]); before != "" {
+				if noneComment == "" {
+					noneCommentIndex = i
+				}
+
 				noneComment = appendNoneComment(noneComment, before)
 			}
 
@@ -487,11 +1008,11 @@ func ConvertSQLLines(lines []string) []string {
 
 			if inlineComment := strings.TrimSpace(inlineCommentContent); inlineComment != "" {
 				if noneComment != "" {
-					converted = append(converted, noneComment)
+					converted, index = append(converted, noneComment), append(index, noneCommentIndex)
 					noneComment = ""
 				}
 
-				converted = append(converted, "-- "+inlineComment)
+				converted, index = append(converted, "-- "+inlineComment), append(index, inlineCommentIndex)
 			}
 
 			l = l[inlineCommentStop+2:]
@@ -504,10 +1025,10 @@ func ConvertSQLLines(lines []string) []string {
 	}
 
 	if noneComment != "" {
-		converted = append(converted, noneComment)
+		converted, index = append(converted, noneComment), append(index, noneCommentIndex)
 	}
 
-	return converted
+	return converted, index
 }
 
 func appendNoneComment(noneComment string, l string) string {
@@ -562,7 +1083,7 @@ func (p *IfSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, er
 
 			processLines, sqlPart, err := ParseDynamicSQL(lines[i+1:], "end", "elseif", "else")
 			if err != nil {
-				return 0, nil, err
+				return 0, nil, shiftLine(err, i+1)
 			}
 
 			ifPart.AddCondition(condition, sqlPart)
@@ -575,7 +1096,7 @@ func (p *IfSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, er
 		if word == "else" {
 			processLines, sqlPart, err := ParseDynamicSQL(lines[i+1:], "end")
 			if err != nil {
-				return 0, nil, err
+				return 0, nil, shiftLine(err, i+1)
 			}
 
 			ifPart.AddElse(sqlPart)
@@ -585,7 +1106,7 @@ func (p *IfSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, er
 
 		processLines, sqlPart, err := ParseDynamicSQL(lines[i:], "end", "elseif", "else")
 		if err != nil {
-			return 0, nil, err
+			return 0, nil, shiftLine(err, i)
 		}
 
 		ifPart.AddCondition(condition, sqlPart)
@@ -596,6 +1117,63 @@ func (p *IfSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, er
 	return 0, nil, fmt.Errorf("no end found for if expr") // nolint:goerr113
 }
 
+// SwitchSQLPartParser defines the Parser of SwitchPart.
+type SwitchSQLPartParser struct {
+	Expr string
+}
+
+// MakeSwitchSQLPartParser makes a SwitchSQLPartParser.
+func MakeSwitchSQLPartParser(expr string) *SwitchSQLPartParser {
+	return &SwitchSQLPartParser{Expr: expr}
+}
+
+// Parse parses the lines to SQLPart.
+func (p *SwitchSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, err error) {
+	if p.Expr == "" {
+		return 0, nil, fmt.Errorf("no expr found for switch") // nolint:goerr113
+	}
+
+	switchPart := &SwitchPart{Expr: p.Expr}
+
+	for i := 0; i < len(lines); {
+		word, rest := "", ""
+
+		if l := lines[i]; strings.HasPrefix(l, "--") {
+			commentLine := strings.TrimSpace(l[2:])
+			word = firstWord(commentLine, 1)
+			rest = strings.TrimSpace(commentLine[len(word):])
+		}
+
+		switch {
+		case word == "end":
+			return i + 2 /* including the switch and end lines */, switchPart, nil
+		case switchPart.Default != nil:
+			return 0, nil, fmt.Errorf("switch %s requires end after default", p.Expr) // nolint:goerr113
+		case word == "case" && rest == "":
+			return 0, nil, fmt.Errorf("switch %s has case without values", p.Expr) // nolint:goerr113
+		case word != "case" && word != "default":
+			return 0, nil, fmt.Errorf("switch %s requires case before %s", p.Expr, lines[i]) // nolint:goerr113
+		}
+
+		processLines, sqlPart, err := ParseDynamicSQL(lines[i+1:], "case", "default", "end")
+		if err != nil {
+			return 0, nil, shiftLine(err, i+1)
+		}
+
+		if word == "case" {
+			switchPart.Cases = append(switchPart.Cases, SwitchCase{Values: rest, Part: sqlPart})
+		} else {
+			switchPart.Default = sqlPart
+		}
+
+		i += processLines + 1
+	}
+
+	return 0, nil, fmt.Errorf("no end found for switch %s", p.Expr) // nolint:goerr113
+}
+
+var _ SQLPartParser = (*SwitchSQLPartParser)(nil)
+
 // MakeLiteralMultiPart makes a MultiPart.
 func MakeLiteralMultiPart(l string) *MultiPart {
 	return &MultiPart{Parts: []SQLPart{&LiteralPart{l}}}
@@ -603,11 +1181,213 @@ func MakeLiteralMultiPart(l string) *MultiPart {
 
 var _ SQLPartParser = (*IfSQLPartParser)(nil)
 
+// TrimPart is the part that trims the evaluated inner text by overrides,
+// and then wraps it with prefix and suffix when it is not empty,
+// like where ... end, set ... end and trim prefix:"(" suffix:")" prefixOverrides:"and|or" ... end.
+type TrimPart struct {
+	Prefix, Suffix                   string
+	PrefixOverrides, SuffixOverrides []string
+	Part                             SQLPart
+}
+
+// MakeWherePart makes a TrimPart for where ... end,
+// which removes the leading and/or, and prepends where when the inner text is not empty.
+func MakeWherePart(part SQLPart) *TrimPart {
+	return &TrimPart{Prefix: "where", PrefixOverrides: []string{"and", "or"}, Part: part}
+}
+
+// MakeSetPart makes a TrimPart for set ... end,
+// which removes the trailing comma, and prepends set when the inner text is not empty.
+func MakeSetPart(part SQLPart) *TrimPart {
+	return &TrimPart{Prefix: "set", SuffixOverrides: []string{","}, Part: part}
+}
+
+// Compile compile the condition int advance.
+func (p *TrimPart) Compile() error { return p.compileExpr(exprScope{}) }
+
+func (p *TrimPart) compileExpr(s exprScope) error { return compilePart(p.Part, s) }
+
+// Eval evaluates the SQL part to a real SQL.
+func (p *TrimPart) Eval(env map[string]interface{}) (string, error) {
+	v, err := p.Part.Eval(env)
+	if err != nil {
+		return "", err
+	}
+
+	return p.trim(v), nil
+}
+
+// trim trims the overrides of the evaluated inner text, and wraps it with prefix and suffix when it is not empty.
+func (p *TrimPart) trim(v string) string {
+	v = strings.TrimSpace(v)
+
+	for _, o := range p.PrefixOverrides {
+		if hasWordPrefix(v, o) {
+			v = strings.TrimSpace(v[len(o):])
+			break
+		}
+	}
+
+	for _, o := range p.SuffixOverrides {
+		if hasWordSuffix(v, o) {
+			v = strings.TrimSpace(v[:len(v)-len(o)])
+			break
+		}
+	}
+
+	if v == "" {
+		return ""
+	}
+
+	if p.Prefix != "" && isWordByte(p.Prefix[len(p.Prefix)-1]) {
+		v = " " + v
+	}
+
+	if p.Suffix != "" && isWordByte(p.Suffix[0]) {
+		v += " "
+	}
+
+	return p.Prefix + v + p.Suffix
+}
+
+// Raw returns the raw content.
+func (p *TrimPart) Raw() string {
+	return p.Prefix + "\n" + p.Part.Raw() + "\n" + p.Suffix
+}
+
+// hasWordPrefix tells whether s starts with the prefix case-insensitively,
+// and a word prefix like and should be followed by a non-word char.
+func hasWordPrefix(s, prefix string) bool {
+	if prefix == "" || len(s) < len(prefix) || !strings.EqualFold(s[:len(prefix)], prefix) {
+		return false
+	}
+
+	return len(s) == len(prefix) || !isWordByte(prefix[len(prefix)-1]) || !isWordByte(s[len(prefix)])
+}
+
+// hasWordSuffix tells whether s ends with the suffix case-insensitively,
+// and a word suffix like and should be preceded by a non-word char.
+func hasWordSuffix(s, suffix string) bool {
+	if suffix == "" || len(s) < len(suffix) || !strings.EqualFold(s[len(s)-len(suffix):], suffix) {
+		return false
+	}
+
+	i := len(s) - len(suffix)
+
+	return i == 0 || !isWordByte(suffix[0]) || !isWordByte(s[i-1])
+}
+
+func isWordByte(b byte) bool {
+	return b == '_' || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+var _ SQLPart = (*TrimPart)(nil)
+
+// TrimSQLPartParser defines the Parser of TrimPart.
+type TrimSQLPartParser struct {
+	Word   string
+	Header string
+}
+
+// MakeTrimSQLPartParser makes a TrimSQLPartParser for the word where, set or trim.
+func MakeTrimSQLPartParser(word, header string) *TrimSQLPartParser {
+	return &TrimSQLPartParser{Word: word, Header: header}
+}
+
+// nolint:gochecknoglobals
+var (
+	trimAttrRe   = regexp.MustCompile(`(\w+)\s*:\s*(?:"([^"]*)"|'([^']*)'|(\S+))`)
+	trimHeaderRe = regexp.MustCompile(`^(?:\s*\w+\s*:\s*(?:"[^"]*"|'[^']*'|\S+))+$`)
+
+	// errNotDirective tells the parsed comment line is not a directive but a comment.
+	errNotDirective = errors.New("not a directive")
+)
+
+// Parse parses the lines to SQLPart, the one without end is left as a comment.
+func (p *TrimSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, err error) {
+	processLines, sqlPart, err := ParseDynamicSQL(lines, "end")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if processLines >= len(lines) {
+		return 0, nil, errNotDirective
+	}
+
+	switch p.Word {
+	case "where":
+		part = MakeWherePart(sqlPart)
+	case "set":
+		part = MakeSetPart(sqlPart)
+	default:
+		if part, err = p.parseTrim(sqlPart); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	return processLines + 2 /* including the directive and end lines */, part, nil
+}
+
+func (p *TrimSQLPartParser) parseTrim(sqlPart SQLPart) (*TrimPart, error) {
+	trimPart := &TrimPart{Part: sqlPart}
+
+	for _, subs := range trimAttrRe.FindAllStringSubmatch(p.Header, -1) {
+		v := subs[2] + subs[3] + subs[4]
+
+		switch subs[1] {
+		case "prefix":
+			trimPart.Prefix = v
+		case "suffix":
+			trimPart.Suffix = v
+		case "prefixOverrides":
+			trimPart.PrefixOverrides = splitOverrides(v)
+		case "suffixOverrides":
+			trimPart.SuffixOverrides = splitOverrides(v)
+		default:
+			return nil, fmt.Errorf("unknown trim attribute %s", subs[1]) // nolint:goerr113
+		}
+	}
+
+	return trimPart, nil
+}
+
+// splitOverrides splits the overrides like and|or.
+func splitOverrides(s string) []string {
+	overrides := make([]string, 0)
+
+	for _, o := range strings.Split(s, "|") {
+		if o = strings.TrimSpace(o); o != "" {
+			overrides = append(overrides, o)
+		}
+	}
+
+	return overrides
+}
+
+var _ SQLPartParser = (*TrimSQLPartParser)(nil)
+
 // CreateParser creates a SQLPartParser.
-// If no parser found, nil returned.
+// If no parser found, nil returned, and so is the line not like the directive, eg. -- for admin only.
 func CreateParser(word string, l string) SQLPartParser {
-	if word == "if" {
+	switch word {
+	case "if":
 		return MakeIfSQLPartParser(l)
+	case "for":
+		if forHeaderRe.MatchString(l) {
+			return MakeForSQLPartParser(l)
+		}
+	case "switch":
+		if _, err := exprparser.Parse(l); err == nil {
+			return MakeSwitchSQLPartParser(l)
+		}
+	case "where", "set":
+		if l == "" { // only the single word line is the directive
+			return MakeTrimSQLPartParser(word, l)
+		}
+	case "trim":
+		if trimHeaderRe.MatchString(l) { // like trim prefix:"(" suffix:")"
+			return MakeTrimSQLPartParser(word, l)
+		}
 	}
 
 	return nil
@@ -2,9 +2,11 @@ package sqlx_test
 
 import (
 	"bufio"
+	"errors"
 	"reflect"
 	"strings"
 	"testing"
+	"testing/fstest"
 
 	"github.com/bingoohuang/sqlx"
 	_ "github.com/mattn/go-sqlite3"
@@ -119,7 +121,7 @@ INSERT INTO users (?, ?, ?)
 
 	for name, query := range got {
 		if query.RawSQL() != expectedQueryMap[name] {
-			t.Errorf("QueryMap()[%s] == '%s', expected '%s'", name, query, expectedQueryMap[name])
+			t.Errorf("QueryMap()[%s] == '%s', expected '%s'", name, query.RawSQL(), expectedQueryMap[name])
 		}
 	}
 }
@@ -165,6 +167,37 @@ func TestParseSQL(t *testing.T) {
 	assert.NotNil(t, err)
 }
 
+func TestParseSQLBinds(t *testing.T) {
+	that := assert.New(t)
+
+	for stmt, expected := range map[string]struct {
+		RawStmt string
+		Vars    []string
+	}{
+		"select id::text, ':x', 'a:b' from t where name = :name -- :c": {
+			RawStmt: "select id::text, ?, 'a:b' from t where name = ? -- :c",
+			Vars:    []string{"x", "name"},
+		},
+		"select '12:30' /* :c */, \\:hour from t where id = ':id' and v := :v|'it''s'": {
+			RawStmt: "select '12:30' /* :c */, :hour from t where id = ? and v := ?",
+			Vars:    []string{"id", "v"},
+		},
+		"select * from t limit :limit|100 offset :offset|0": {
+			RawStmt: "select * from t limit ? offset ?",
+			Vars:    []string{"limit", "offset"},
+		},
+		"select :a||:b, `c:d`, \"e:f\" from t": {
+			RawStmt: "select ?||?, `c:d`, \"e:f\" from t",
+			Vars:    []string{"a", "b"},
+		},
+	} {
+		parsed, err := sqlx.ParseSQL("", stmt)
+		that.Nil(err)
+		that.Equal(expected.RawStmt, parsed.RawStmt)
+		that.Equal(expected.Vars, parsed.Vars)
+	}
+}
+
 func TestConvertSQLLines(t *testing.T) {
 	that := assert.New(t)
 
@@ -360,4 +393,400 @@ func TestParseDynamicSQL(t *testing.T) {
 			},
 		}}, part)
 	}
+
+	{
+		lines, part, err := sqlx.ParseDynamicSQL([]string{`-- for x, i in xs sep ", "`, "-- if i > 0", "b", "-- end",
+			":x", "-- end"})
+		that.Nil(err)
+		that.Equal(6, lines)
+		that.Nil(part.Compile())
+
+		s, err := part.Eval(map[string]interface{}{"xs": []int{1, 2}})
+		that.Nil(err)
+		that.Equal(":_sqlx_for_0, b :_sqlx_for_1", s)
+	}
+
+	{
+		_, _, err := sqlx.ParseDynamicSQL([]string{"-- for x in xs", "a"})
+		that.Error(err)
+	}
+
+	{
+		// the comment not like for item, i in items is left as a comment.
+		lines, part, err := sqlx.ParseDynamicSQL([]string{"select a from t", "-- for admin only", "where b = 1"})
+		that.Nil(err)
+		that.Equal(3, lines)
+
+		s, err := part.Eval(map[string]interface{}{})
+		that.Nil(err)
+		that.Equal("select a from t where b = 1", s)
+	}
+
+	{
+		_, part, err := sqlx.ParseDynamicSQL([]string{`-- trim prefix:"(" suffix:")" prefixOverrides:"and|or"`,
+			"-- if a", "and a = 1", "-- end", "-- if b", "or b = 1", "-- end", "-- end"})
+		that.Nil(err)
+		that.Nil(part.Compile())
+
+		s, err := part.Eval(map[string]interface{}{"a": false, "b": true})
+		that.Nil(err)
+		that.Equal("(b = 1)", s)
+
+		s, err = part.Eval(map[string]interface{}{"a": false, "b": false})
+		that.Nil(err)
+		that.Equal("", s)
+	}
+
+	{
+		lines, part, err := sqlx.ParseDynamicSQL([]string{"-- switch t", "-- case 1, 2", "a", "-- case 3", "b",
+			"-- end"})
+		that.Nil(err)
+		that.Equal(6, lines)
+		that.Nil(part.Compile())
+
+		s, err := part.Eval(map[string]interface{}{"t": int64(2)})
+		that.Nil(err)
+		that.Equal("a", s)
+
+		s, err = part.Eval(map[string]interface{}{"t": 4})
+		that.Nil(err)
+		that.Equal("", s)
+
+		_, _, err = sqlx.ParseDynamicSQL([]string{"-- switch t", "-- case 1", "a"})
+		that.Error(err)
+	}
+
+	{
+		// the comments not like trim attributes, or without end, are left as comments.
+		_, part, err := sqlx.ParseDynamicSQL([]string{"-- trim the spaces", "select a from t", "where b = 1",
+			"-- where", "-- set"})
+		that.Nil(err)
+
+		s, err := part.Eval(map[string]interface{}{})
+		that.Nil(err)
+		that.Equal("select a from t where b = 1", s)
+	}
+
+	{
+		// the comment not like switch expr is left as a comment.
+		_, part, err := sqlx.ParseDynamicSQL([]string{"select a from t", "-- switch to replica later", "where b = 1"})
+		that.Nil(err)
+
+		s, err := part.Eval(map[string]interface{}{})
+		that.Nil(err)
+		that.Equal("select a from t where b = 1", s)
+	}
+}
+
+func TestInclude(t *testing.T) {
+	that := assert.New(t)
+
+	dot, err := sqlx.DotSQLLoadFiles("testdata/include.sql", "testdata/fragments.sql")
+	that.Nil(err)
+	that.Equal("select\np.id, p.age\nfrom person p where\nage >= 18\norder by id", dot.Sqls["ListAdults"].RawSQL())
+
+	_, err = sqlx.DotSQLLoadFile("testdata/include.sql")
+	that.EqualError(err, "dotsql: fragment personCols not found at testdata/include.sql:3")
+
+	_, err = sqlx.DotSQLLoadString(`
+-- fragment: a
+-- include: b
+-- fragment: b
+x
+-- include: a
+-- name: q
+select
+-- include: a
+`)
+	that.EqualError(err, "dotsql: include cycle a -> b -> a at line 6")
+
+	_, err = sqlx.DotSQLLoadString(`
+-- fragment: cols
+{{alias}}.id
+-- fragment: aliased
+-- include: cols alias: {{t}}
+-- name: q
+select
+-- include: aliased t: p
+from person p
+-- name: r
+select
+-- include: cols
+from person p
+`)
+	that.EqualError(err, "dotsql: include cols parameter {{alias}} not bound at line 12")
+
+	dot, err = sqlx.DotSQLLoadString(`
+-- fragment: cols
+{{alias}}.id
+-- fragment: aliased
+-- include: cols alias: {{t}}
+-- name: q
+select
+-- include: aliased t: p
+from person p
+`)
+	that.Nil(err)
+	that.Equal("select\np.id\nfrom person p", dot.Sqls["q"].RawSQL())
+}
+
+func TestDotSQLLoadFS(t *testing.T) {
+	that := assert.New(t)
+
+	fsys := fstest.MapFS{
+		"sql/user.sql":       {Data: []byte("-- name: FindByID\nselect * from user where id = :1;")},
+		"sql/order.sql":      {Data: []byte("-- name: FindOrder\nselect * from orders where id = :1;")},
+		"sql/more/stat.sql":  {Data: []byte("-- name: Count\nselect count(*) from user;")},
+		"sql/more/notes.txt": {Data: []byte("-- name: Ignored\nselect 1;")},
+	}
+
+	dot, err := sqlx.DotSQLLoadFS(fsys, "sql")
+	that.Nil(err)
+
+	part, err := dot.Raw("user.FindByID")
+	that.Nil(err)
+	that.Equal("select * from user where id = :1", part.Raw())
+
+	part, err = dot.Raw("FindOrder")
+	that.Nil(err)
+	that.Equal("select * from orders where id = :1", part.Raw())
+
+	_, err = dot.Raw("order.FindOrder")
+	that.Nil(err)
+
+	_, err = dot.Raw("Count")
+	that.Nil(err)
+
+	_, err = dot.Raw("Ignored")
+	that.Error(err)
+
+	fsys["sql/more/user.sql"] = &fstest.MapFile{Data: []byte("\n-- name: FindByID\nselect 1;")}
+	_, err = sqlx.DotSQLLoadFS(fsys, "sql/*.sql", "sql/more/*.sql")
+	that.EqualError(err, "dotsql: duplicate name user.FindByID at sql/more/user.sql:2, first defined at sql/user.sql:1")
+
+	delete(fsys, "sql/more/user.sql")
+	fsys["sql/order.sql"] = &fstest.MapFile{Data: []byte("-- name: FindByID\nselect * from orders where id = :1;")}
+	_, err = sqlx.DotSQLLoadFS(fsys, "sql/*.sql")
+	that.EqualError(err, "dotsql: duplicate name FindByID at sql/user.sql:1, first defined at sql/order.sql:1")
+
+	fsys["sql/order.sql"] = &fstest.MapFile{Data: []byte("-- name: FindOrder\nselect 1;\n\n-- name: FindOrder\nselect 2;")}
+	_, err = sqlx.DotSQLLoadFS(fsys, "sql/*.sql")
+	that.EqualError(err, "dotsql: duplicate name FindOrder at sql/order.sql:4, first defined at sql/order.sql:1")
+
+	_, err = sqlx.DotSQLLoadFS(fsys, "sql/none/*.sql")
+	that.Error(err)
+}
+
+func TestDotSQLDBType(t *testing.T) {
+	that := assert.New(t)
+
+	dot, err := sqlx.DotSQLLoadString(`
+-- name: Now
+select now();
+
+-- name: Now dbtype: sqlite
+select datetime('now');
+
+-- name: Now dbtype: oracle,sqlserver
+select sysdate from dual;
+`)
+	that.Nil(err)
+
+	for name, expected := range map[string]string{
+		"Now":           "select now()",
+		"Now@mysql":     "select now()",
+		"Now@sqlite3":   "select datetime('now')",
+		"Now@sqlite":    "select datetime('now')",
+		"Now@godror":    "select sysdate from dual",
+		"Now@sqlserver": "select sysdate from dual",
+	} {
+		part, err := dot.Raw(name)
+		that.Nil(err)
+		that.Equal(expected, part.Raw(), name)
+	}
+
+	dot, err = sqlx.DotSQLLoadString("-- name: Now dbtype: sqlite\nselect datetime('now');")
+	that.Nil(err)
+
+	_, err = dot.Raw("Now@mysql")
+	that.EqualError(err, "dotsql: 'Now' could not be found")
+}
+
+func TestDotSQLExprFuncs(t *testing.T) {
+	that := assert.New(t)
+
+	dot, err := sqlx.DotSQLLoadString(`
+-- name: Find
+select * from person
+-- where
+-- if len(ids) > 0
+and id in (:ids)
+-- end
+-- if isNotBlank(name)
+and name = :name
+-- end
+-- if tags contains 'vip' && hasPrefix(addr, 'bj') && !includes(tags, 'x')
+and vip = 1
+-- end
+-- if isEmpty(nick) || isAdult(age)
+and adult = 1
+-- end
+-- end
+`)
+	that.Nil(err)
+
+	dot.Use(sqlx.ExprFunc("isAdult", func(params ...interface{}) (interface{}, error) {
+		return params[0].(int) >= 18, nil
+	}, new(func(int) bool)))
+
+	part, err := dot.Raw("Find")
+	that.Nil(err)
+
+	for expected, env := range map[string]map[string]interface{}{
+		"select * from person": {"ids": nil, "name": "  ", "age": 10, "nick": "x"},
+		"select * from person where id in (:ids) and name = :name": {
+			"ids": []string{"1"}, "name": "bingoo", "age": 10, "nick": "x",
+		},
+		"select * from person where vip = 1 and adult = 1": {
+			"tags": []string{"vip"}, "addr": "bj-01", "age": 10,
+		},
+		"select * from person where adult = 1": {"tags": []string{"v"}, "addr": "bj-01", "age": 20, "nick": "x"},
+	} {
+		s, err := part.Eval(env)
+		that.Nil(err)
+		that.Equal(expected, s)
+	}
+}
+
+func TestDotSQLIdents(t *testing.T) {
+	that := assert.New(t)
+
+	dot, err := sqlx.DotSQLLoadString(`
+-- name: ListLogs ident: ^(log_\d{6}|name|age)$
+select #{col} from ${'log_' + month}
+-- if desc
+order by #{col} desc
+-- end
+`)
+	that.Nil(err)
+
+	part, err := dot.Raw("ListLogs")
+	that.Nil(err)
+
+	s, err := part.Eval(map[string]interface{}{"month": "202610", "col": "name", "desc": false})
+	that.Nil(err)
+	that.Equal("select `name` from log_202610", s)
+
+	s, err = part.Eval(map[string]interface{}{"month": "202610", "col": "age", "desc": true})
+	that.Nil(err)
+	that.Equal("select `age` from log_202610 order by `age` desc", s)
+
+	for _, env := range []map[string]interface{}{
+		{"month": "202610; drop table log_202610", "col": "name", "desc": false},
+		{"month": "202610", "col": "`name`", "desc": false},
+		{"month": "202610", "col": "addr", "desc": false},
+		{"month": "202610", "col": 1, "desc": false},
+	} {
+		_, err := part.Eval(env)
+		that.Error(err)
+	}
+
+	dot, err = sqlx.DotSQLLoadString(`
+-- name: ListCols
+select
+-- for c in cols sep ","
+#{c}
+-- end
+from log
+`)
+	that.Nil(err)
+
+	_, err = dot.Raw("ListCols")
+	that.EqualError(err, `line 4: identifier #{c} is not supported in for c in cols sep ",", interpolate it out of the loop`)
+
+	dot, err = sqlx.DotSQLLoadString(`
+-- name: ListTemplates
+select ${'log_' + month}.id from ${'log_' + month} where tpl = '${user.name}' and tpl2 <> 'it''s #{x}' and
+-- for c in cols sep " or "
+body like '%${c}%'
+-- end
+`)
+	that.Nil(err)
+
+	part, err = dot.Raw("ListTemplates")
+	that.Nil(err)
+
+	s, err = part.Eval(map[string]interface{}{"month": "202610", "cols": []string{"a"}})
+	that.Nil(err)
+	that.Equal("select log_202610.id from log_202610 where tpl = '${user.name}' and tpl2 <> 'it''s #{x}' and "+
+		"body like '%${c}%'", s)
+}
+
+func TestDotSQLDeclarations(t *testing.T) {
+	that := assert.New(t)
+
+	dot, err := sqlx.DotSQLLoadString(`
+-- name: FindUsers
+-- param: name string
+-- param: born time.Time
+-- result: []User
+select id, name from user where name = :1 and born > :2;
+
+-- name: DeleteUser
+-- param: id int64
+delete from user where id = :1;
+
+-- name: UpdateUser
+update user set name = :1
+-- param: id int64
+where id = :2;
+`)
+	that.Nil(err)
+
+	find := dot.Sqls["FindUsers"]
+	that.Equal([]sqlx.DotSQLParam{{Name: "name", Type: "string"}, {Name: "born", Type: "time.Time"}}, find.Params)
+	that.Equal("[]User", find.Result)
+	that.Equal("select id, name from user where name = :1 and born > :2", find.RawSQL())
+
+	del := dot.Sqls["DeleteUser"]
+	that.Equal([]sqlx.DotSQLParam{{Name: "id", Type: "int64"}}, del.Params)
+	that.Equal("", del.Result)
+	that.Equal("delete from user where id = :1", del.RawSQL())
+
+	update := dot.Sqls["UpdateUser"]
+	that.Nil(update.Params)
+	that.Equal("update user set name = :1\n-- param: id int64\nwhere id = :2", update.RawSQL())
+}
+
+func TestDotSQLErrorPosition(t *testing.T) {
+	that := assert.New(t)
+
+	dot, err := sqlx.DotSQLLoadFile("testdata/errors.sql")
+	that.Nil(err)
+
+	// the where without end is left as a comment.
+	part, err := dot.Raw("MissingEnd")
+	that.Nil(err)
+	that.Nil(part.Compile())
+
+	s, err := part.Eval(map[string]interface{}{"age": 10, "name": ""})
+	that.Nil(err)
+	that.Equal("select * from person and age = :age", s)
+
+	_, err = dot.Raw("BadSwitch")
+	that.EqualError(err, "testdata/errors.sql:30: switch age requires case before and 2 = 2")
+
+	_, err = dot.Raw("BadExpr")
+	that.Error(err)
+	that.True(strings.HasPrefix(err.Error(), "testdata/errors.sql:13: "))
+
+	part, err = dot.Raw("NotBool")
+	that.Nil(err)
+
+	_, err = part.Eval(map[string]interface{}{"age": 10})
+
+	var dotErr *sqlx.DotSQLError
+	that.True(errors.As(err, &dotErr))
+	that.Equal(sqlx.DotSQLError{Name: "NotBool", File: "testdata/errors.sql", Line: 18, Err: dotErr.Err}, *dotErr)
+	that.EqualError(err, "testdata/errors.sql:18: age is not a bool expression")
 }
@@ -0,0 +1,97 @@
+package sqlx
+
+import (
+	"errors"
+	"strings"
+)
+
+// DotSQLError is the error of the dotsql statement with its position, like users.sql:42.
+type DotSQLError struct {
+	// Name is the name of the statement.
+	Name string
+	// File is the file name of the statement, empty when not loaded from a file.
+	File string
+	// Line is the line number where the error occurred.
+	Line int
+	Err  error
+}
+
+// Error returns the error prefixed by the position.
+func (e *DotSQLError) Error() string { return position(e.File, e.Line) + ": " + e.Err.Error() }
+
+// Unwrap returns the underlying error.
+func (e *DotSQLError) Unwrap() error { return e.Err }
+
+// lineError is the parsing error at the index of the lines passed to ParseDynamicSQL.
+type lineError struct {
+	index int
+	err   error
+}
+
+func (e *lineError) Error() string { return e.err.Error() }
+func (e *lineError) Unwrap() error { return e.err }
+
+// shiftLine shifts the index of the lineError from lines[offset:] to lines.
+func shiftLine(err error, offset int) error {
+	if le, ok := err.(*lineError); ok {
+		le.index += offset
+	}
+
+	return err
+}
+
+// exprError is the error of the expression, which is located by the line where the expression is written.
+type exprError struct {
+	expr string
+	err  error
+}
+
+func (e *exprError) Error() string { return e.err.Error() }
+func (e *exprError) Unwrap() error { return e.err }
+
+// dotSQLSource is the source of the dynamic SQL to locate the errors.
+type dotSQLSource struct {
+	item DotSQLItem
+	// index is the index of the content of the converted lines.
+	index []int
+}
+
+// errorAt wraps the error by a *DotSQLError with the position where the error occurred,
+// the error is returned as is when the source is unknown, like the SQL of sql tag.
+func (s *dotSQLSource) errorAt(err error) error {
+	var de *DotSQLError
+	if s == nil || err == nil || s.item.File == "" && s.item.Line == 0 || errors.As(err, &de) {
+		return err
+	}
+
+	return &DotSQLError{Name: s.item.Name, File: s.item.File, Line: s.lineOf(err), Err: err}
+}
+
+// lineOf returns the line number where the error occurred, or the line of the statement when unknown.
+func (s *dotSQLSource) lineOf(err error) int {
+	var (
+		le *lineError
+		ee *exprError
+	)
+
+	switch {
+	case errors.As(err, &le) && le.index < len(s.index):
+		if line := lineOf(s.item, s.index[le.index]); line > 0 {
+			return line
+		}
+	case errors.As(err, &ee):
+		// find in the directives first, the expression may also appear in the SQL like age of -- if age > 0.
+		for _, directive := range []bool{true, false} {
+			for i, l := range s.item.Content {
+				isDirective := strings.HasPrefix(l, "--") || strings.Contains(l, "/*")
+				if (!directive || isDirective) && strings.Contains(l, ee.expr) {
+					if line := lineOf(s.item, i); line > 0 {
+						return line
+					}
+				}
+			}
+		}
+	}
+
+	return s.item.Line
+}
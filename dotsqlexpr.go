@@ -0,0 +1,265 @@
+package sqlx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// DotSQLOption is the option to compile the dotsql expressions, like ExprFunc and ExprOperator,
+// and any expr.Option is accepted, like expr.Timezone.
+type DotSQLOption = expr.Option
+
+// ExprFunc registers the custom function to the dotsql expressions, like isAdult(age),
+// the types like new(func(int) bool) are used in type checking, see expr.Function.
+func ExprFunc(name string, fn func(params ...interface{}) (interface{}, error), types ...interface{}) DotSQLOption {
+	return expr.Function(name, fn, types...)
+}
+
+// ExprOperator overloads the operator by the functions registered by ExprFunc, see expr.Operator.
+func ExprOperator(operator string, fns ...string) DotSQLOption {
+	return expr.Operator(operator, fns...)
+}
+
+// Use adds the options to compile the expressions of the statements looked up later.
+func (d *DotSQL) Use(opts ...DotSQLOption) *DotSQL {
+	d.exprOpts = append(d.exprOpts, opts...)
+	return d
+}
+
+// nolint:gochecknoglobals
+var builtinExprFuncs = []DotSQLOption{
+	ExprFunc("isEmpty", func(params ...interface{}) (interface{}, error) {
+		return isEmptyValue(params[0]), nil
+	}, new(func(interface{}) bool)),
+	ExprFunc("isNotEmpty", func(params ...interface{}) (interface{}, error) {
+		return !isEmptyValue(params[0]), nil
+	}, new(func(interface{}) bool)),
+	ExprFunc("isBlank", func(params ...interface{}) (interface{}, error) {
+		return isBlankValue(params[0]), nil
+	}, new(func(interface{}) bool)),
+	ExprFunc("isNotBlank", func(params ...interface{}) (interface{}, error) {
+		return !isBlankValue(params[0]), nil
+	}, new(func(interface{}) bool)),
+	ExprFunc("nonZero", func(params ...interface{}) (interface{}, error) {
+		v := indirectValue(params[0])
+		return v.IsValid() && !v.IsZero(), nil
+	}, new(func(interface{}) bool)),
+	// contains is an operator of expr, it is overloaded by includes for the slices, maps and nil.
+	ExprFunc("includes", func(params ...interface{}) (interface{}, error) {
+		return containsValue(params[0], params[1]), nil
+	}, new(func(interface{}, interface{}) bool)),
+	ExprOperator("contains", "includes"),
+	ExprFunc("hasPrefix", func(params ...interface{}) (interface{}, error) {
+		s, prefix := indirectValue(params[0]), indirectValue(params[1])
+		return s.Kind() == reflect.String && prefix.Kind() == reflect.String &&
+			strings.HasPrefix(s.String(), prefix.String()), nil
+	}, new(func(interface{}, interface{}) bool)),
+	ExprFunc("len", func(params ...interface{}) (interface{}, error) {
+		v := indirectValue(params[0])
+		switch v.Kind() {
+		case reflect.Invalid:
+			return 0, nil
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array, reflect.Chan:
+			return v.Len(), nil
+		default:
+			return nil, fmt.Errorf("invalid argument for len (type %v)", v.Type()) // nolint:goerr113
+		}
+	}, new(func(interface{}) int)),
+}
+
+// indirectValue returns the value pointed to, invalid for nil.
+func indirectValue(v interface{}) reflect.Value {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		rv = rv.Elem()
+	}
+
+	return rv
+}
+
+// isEmptyValue tells whether the value is nil, or an empty string, slice, map or array.
+func isEmptyValue(v interface{}) bool {
+	switch rv := indirectValue(v); rv.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.String, reflect.Slice, reflect.Map, reflect.Array, reflect.Chan:
+		return rv.Len() == 0
+	default:
+		return false
+	}
+}
+
+// isBlankValue tells whether the value is empty, or a string of only white spaces.
+func isBlankValue(v interface{}) bool {
+	if rv := indirectValue(v); rv.Kind() == reflect.String {
+		return strings.TrimSpace(rv.String()) == ""
+	}
+
+	return isEmptyValue(v)
+}
+
+// containsValue tells whether the string contains the substring, the slice/array contains the element,
+// or the map contains the key.
+func containsValue(collection, x interface{}) bool {
+	rv := indirectValue(collection)
+
+	switch rv.Kind() {
+	case reflect.String:
+		sub := indirectValue(x)
+		return sub.Kind() == reflect.String && strings.Contains(rv.String(), sub.String())
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < rv.Len(); i++ {
+			if switchEquals(rv.Index(i).Interface(), x) {
+				return true
+			}
+		}
+	case reflect.Map:
+		for _, k := range rv.MapKeys() {
+			if switchEquals(k.Interface(), x) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// exprScope is the scope to compile the expressions, with the options like the custom functions,
+// and the env typed by the parameters of the dao func, nil for untyped.
+type exprScope struct {
+	opts []DotSQLOption
+	env  map[string]interface{}
+	// undefined allows the undefined variables when the env is incomplete, like a map parameter.
+	undefined bool
+}
+
+// compile compiles the expression with the built-in functions and the options in the scope.
+func (s exprScope) compile(e string) (*vm.Program, error) {
+	opts := make([]expr.Option, 0, len(builtinExprFuncs)+len(s.opts)+2)
+	opts = append(append(opts, builtinExprFuncs...), s.opts...)
+
+	if s.env != nil {
+		opts = append(opts, expr.Env(s.env))
+
+		if s.undefined {
+			opts = append(opts, expr.AllowUndefinedVariables())
+		}
+	}
+
+	return expr.Compile(e, opts...)
+}
+
+// withOptions returns the scope with the options added.
+func (s exprScope) withOptions(opts []DotSQLOption) exprScope {
+	if len(opts) > 0 {
+		s.opts = append(append([]DotSQLOption{}, s.opts...), opts...)
+	}
+
+	return s
+}
+
+// forScope returns the scope of the loop body, the item and index variables are typed by the compiled items.
+func (s exprScope) forScope(p *ForPart) exprScope {
+	if s.env != nil {
+		s.env = p.loopEnv(s.env, p.CompiledExpr.Node().Type())
+	}
+
+	return s
+}
+
+// loopEnv returns the env of the loop body, the item is typed by the element of the items type.
+func (p *ForPart) loopEnv(env map[string]interface{}, items reflect.Type) map[string]interface{} {
+	var item interface{}
+
+	if items != nil {
+		if k := items.Kind(); k == reflect.Slice || k == reflect.Array {
+			item = reflect.Zero(items.Elem()).Interface()
+		}
+	}
+
+	loopEnv := make(map[string]interface{}, len(env)+2)
+	for k, v := range env {
+		loopEnv[k] = v
+	}
+
+	loopEnv[p.Item] = item
+	if p.Index != "" {
+		loopEnv[p.Index] = 0
+	}
+
+	return loopEnv
+}
+
+// exprCompiler is the SQLPart whose expressions are compiled in the scope.
+type exprCompiler interface {
+	compileExpr(s exprScope) error
+}
+
+// compilePart compiles the part in the scope, or by its Compile when it is not an exprCompiler.
+func compilePart(part SQLPart, s exprScope) error {
+	if c, ok := part.(exprCompiler); ok {
+		return c.compileExpr(s)
+	}
+
+	return part.Compile()
+}
+
+// funcInTypes returns the parameter types of the dao func, without the leading context.Context.
+func funcInTypes(f StructField) []reflect.Type {
+	inTypes := make([]reflect.Type, 0, f.Type.NumIn())
+
+	for i := 0; i < f.Type.NumIn(); i++ {
+		if t := f.Type.In(i); i > 0 || t != _ctxType {
+			inTypes = append(inTypes, t)
+		}
+	}
+
+	return inTypes
+}
+
+// exprScopeOf returns the scope whose env is typed by the parameters of the dao func, like _1, _2 for the seq binds,
+// or the fields of the struct for the named binds, and the bean is the struct type, nil when unknown like a map.
+func (p *SQLParsed) exprScopeOf(inTypes []reflect.Type) (scope exprScope, bean reflect.Type) {
+	scope = exprScope{opts: p.opt.exprOpts, env: make(map[string]interface{})}
+
+	if !p.isBindBy(ByName) {
+		for i, t := range inTypes {
+			scope.env[fmt.Sprintf("_%d", i+1)] = reflect.Zero(t).Interface()
+		}
+
+		return scope, nil
+	}
+
+	bean = inTypes[0]
+	if bean.Kind() == reflect.Slice {
+		bean = bean.Elem()
+	}
+
+	if bean.Kind() != reflect.Struct {
+		scope.undefined = true
+		return scope, nil
+	}
+
+	scope.env = p.createNamedMap(reflect.New(bean).Elem())
+
+	return scope, bean
+}
+
+// compileExpr compiles the expressions of the SQL with the env typed by the parameters of the dao func.
+func (p *SQLParsed) compileExpr(f StructField) error {
+	inTypes := funcInTypes(f)
+	if err := p.checkFuncInOut(len(inTypes), f); err != nil {
+		return err
+	}
+
+	scope, _ := p.exprScopeOf(inTypes)
+	if err := compilePart(p.SQL, scope); err != nil {
+		return fmt.Errorf("compile expressions of %s error %w", p.ID, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,246 @@
+package sqlx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// evalVarsKey is the env key of the evalVars.
+const evalVarsKey = "_sqlx_vars"
+
+// evalVars holds the bind values generated in evaluating the dynamic SQL, like the per-iteration binds of for.
+type evalVars map[string]interface{}
+
+// evalVarsOf returns the evalVars in the env, it is created when absent.
+func evalVarsOf(env map[string]interface{}) evalVars {
+	if vars, ok := env[evalVarsKey].(evalVars); ok {
+		return vars
+	}
+
+	vars := make(evalVars)
+	env[evalVarsKey] = vars
+
+	return vars
+}
+
+// add adds the bind value and returns its bind name.
+func (v evalVars) add(value interface{}) string {
+	name := "_sqlx_for_" + strconv.Itoa(len(v))
+	v[name] = value
+
+	return name
+}
+
+// ForPart is the part that has the format of for item, i in items sep "," ... end.
+// The part is repeated for each element of the items expression (a slice or an array),
+// the item and index variables are available to the nested expressions,
+// and the binds like :item.id or :item are bound to the element of the current iteration.
+type ForPart struct {
+	Item, Index  string
+	Items        string
+	Sep          string
+	CompiledExpr *vm.Program
+	Part         SQLPart
+
+	bindRe *regexp.Regexp
+}
+
+// Compile compile the condition int advance.
+func (p *ForPart) Compile() error { return p.compileExpr(exprScope{}) }
+
+func (p *ForPart) compileExpr(s exprScope) (err error) {
+	if p.CompiledExpr, err = s.compile(p.Items); err != nil {
+		return &exprError{expr: p.Items, err: err}
+	}
+
+	return compilePart(p.Part, s.forScope(p))
+}
+
+// Eval evaluates the SQL part to a real SQL.
+func (p *ForPart) Eval(env map[string]interface{}) (string, error) {
+	output, err := expr.Run(p.CompiledExpr, env)
+	if err != nil {
+		return "", &exprError{expr: p.Items, err: err}
+	}
+
+	if output == nil {
+		return "", nil
+	}
+
+	items := reflect.ValueOf(output)
+	if k := items.Kind(); k != reflect.Slice && k != reflect.Array {
+		return "", &exprError{expr: p.Items, err: fmt.Errorf("%s is not a slice or an array", p.Items)} // nolint:goerr113
+	}
+
+	vars := evalVarsOf(env)
+	defer restoreEnv(env, p.Item, p.Index)()
+
+	parts := make([]string, 0, items.Len())
+
+	for i := 0; i < items.Len(); i++ {
+		item := items.Index(i).Interface()
+		env[p.Item] = item
+
+		if p.Index != "" {
+			env[p.Index] = i
+		}
+
+		s, err := p.Part.Eval(env)
+		if err != nil {
+			return "", err
+		}
+
+		if s, err = p.bindLoopVars(s, item, i, vars); err != nil {
+			return "", err
+		}
+
+		parts = append(parts, s)
+	}
+
+	return strings.Join(parts, p.Sep), nil
+}
+
+// bindLoopVars replaces the binds of the loop variables by the generated binds of the current iteration.
+func (p *ForPart) bindLoopVars(s string, item interface{}, index int, vars evalVars) (string, error) {
+	var err error
+
+	s = p.bindRe.ReplaceAllStringFunc(s, func(bind string) string {
+		path := strings.Split(bind[1:], ".")
+		value := interface{}(index)
+
+		if path[0] == p.Item {
+			var e error
+			if value, e = bindPath(item, path[1:]); e != nil && err == nil {
+				err = fmt.Errorf("bind %s error %w", bind, e)
+			}
+		}
+
+		return ":" + vars.add(value)
+	})
+
+	return s, err
+}
+
+// bindPath gets the value of the path like id or addr.city of the struct/map value.
+func bindPath(value interface{}, path []string) (interface{}, error) {
+	for _, name := range path {
+		v := reflect.Indirect(reflect.ValueOf(value))
+		if k := v.Kind(); k != reflect.Struct && k != reflect.Map {
+			return nil, fmt.Errorf("%s is not found in %T", name, value) // nolint:goerr113
+		}
+
+		field, ok := columnValue(v, name)
+		if !ok {
+			return nil, fmt.Errorf("%s is not found in %T", name, value) // nolint:goerr113
+		}
+
+		value = field
+	}
+
+	return value, nil
+}
+
+// restoreEnv returns the func to restore the env of the keys to their current values.
+func restoreEnv(env map[string]interface{}, keys ...string) func() {
+	saved := make(map[string]interface{})
+
+	for _, k := range keys {
+		if v, ok := env[k]; ok {
+			saved[k] = v
+		}
+	}
+
+	return func() {
+		for _, k := range keys {
+			if v, ok := saved[k]; ok {
+				env[k] = v
+			} else {
+				delete(env, k)
+			}
+		}
+	}
+}
+
+// Raw returns the raw content, the binds of loop variables are replaced by the bind of items,
+// like :1 for items _1 or :ids for items ids, to tell the bind mode.
+func (p *ForPart) Raw() string {
+	bind := "?"
+
+	if subs := seqItemsRe.FindStringSubmatch(p.Items); subs != nil {
+		bind = ":" + subs[1]
+	} else if identRe.MatchString(p.Items) {
+		bind = ":" + p.Items
+	}
+
+	return p.bindRe.ReplaceAllLiteralString(p.Part.Raw(), bind)
+}
+
+var _ SQLPart = (*ForPart)(nil)
+
+// ForSQLPartParser defines the Parser of ForPart.
+type ForSQLPartParser struct {
+	Header string
+}
+
+// MakeForSQLPartParser makes a ForSQLPartParser.
+func MakeForSQLPartParser(header string) *ForSQLPartParser {
+	return &ForSQLPartParser{Header: header}
+}
+
+// nolint:gochecknoglobals
+var (
+	identRe     = regexp.MustCompile(`^\w+$`)
+	seqItemsRe  = regexp.MustCompile(`^_(\d+)$`)
+	forHeaderRe = regexp.MustCompile(`^(\w+)(?:\s*,\s*(\w+))?\s+in\s+(.+?)(?:\s+sep\s+("[^"]*"|'[^']*'|\S+))?$`)
+)
+
+// Parse parses the lines to SQLPart.
+func (p *ForSQLPartParser) Parse(lines []string) (partLines int, part SQLPart, err error) {
+	subs := forHeaderRe.FindStringSubmatch(p.Header)
+	if subs == nil {
+		return 0, nil, fmt.Errorf("bad for %s, should be like for item, i in items sep \",\"", p.Header) // nolint:goerr113
+	}
+
+	forPart := &ForPart{Item: subs[1], Index: subs[2], Items: subs[3], Sep: subs[4]}
+
+	if s, err := strconv.Unquote(forPart.Sep); err == nil {
+		forPart.Sep = s
+	} else if l := len(forPart.Sep); l >= 2 && forPart.Sep[0] == '\'' && forPart.Sep[l-1] == '\'' {
+		forPart.Sep = forPart.Sep[1 : l-1]
+	}
+
+	loopVars := regexp.QuoteMeta(forPart.Item)
+	if forPart.Index != "" {
+		loopVars += "|" + regexp.QuoteMeta(forPart.Index)
+	}
+
+	forPart.bindRe = regexp.MustCompile(`:(?:` + loopVars + `)(?:\.\w+)*\b`)
+
+	processLines, sqlPart, err := ParseDynamicSQL(lines, "end")
+	if err != nil {
+		return 0, nil, err
+	}
+
+	if processLines >= len(lines) {
+		return 0, nil, fmt.Errorf("no end found for for %s", p.Header) // nolint:goerr113
+	}
+
+	// the identifiers are interpolated after the whole SQL is evaluated, when the loop variables are out of scope.
+	raw := sqlPart.Raw()
+	if refs := codeIdentRefs(raw); len(refs) > 0 {
+		return 0, nil, fmt.Errorf("identifier %s is not supported in for %s, "+ // nolint:goerr113
+			"interpolate it out of the loop", raw[refs[0][0]:refs[0][1]], p.Header)
+	}
+
+	forPart.Part = sqlPart
+
+	return processLines + 2 /* including the for and end lines */, forPart, nil
+}
+
+var _ SQLPartParser = (*ForSQLPartParser)(nil)
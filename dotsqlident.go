@@ -0,0 +1,169 @@
+package sqlx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/expr-lang/expr"
+	"github.com/expr-lang/expr/vm"
+)
+
+// dbTypeKey is the env key of the dbtype of the dao, which tells how to quote the identifiers.
+const dbTypeKey = "_sqlx_dbtype"
+
+// nolint:gochecknoglobals
+var (
+	// identRefRe matches the identifier reference like ${table} and #{col} at the start, see codeIdentRefs,
+	// the ${} one is inserted as is, and the #{} one is quoted by the dbtype.
+	identRefRe = regexp.MustCompile(`^([$#])\{\s*([^{}]+?)\s*}`)
+	// safeIdentRe matches the identifiers which are safe to be inserted, like log_202610 or db1.log_202610.
+	safeIdentRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+)
+
+// identInterpolator interpolates the identifiers like the monthly partition tables log_202610
+// into the evaluated SQL, the ident attr like ident: ^log_\d{6}$ is the allowlist of the identifiers,
+// and a declared set can be written like ident: ^(name|age)$.
+// The identifiers should always be like safeIdentRe, anything else is refused.
+type identInterpolator struct {
+	allow    *regexp.Regexp
+	programs map[string]*vm.Program
+}
+
+// compileIdents compiles the expressions of the identifier references in the raw SQL.
+func compileIdents(raw string, attrs map[string]string, s exprScope) (*identInterpolator, error) {
+	refs := codeIdentRefs(raw)
+	if len(refs) == 0 {
+		return nil, nil
+	}
+
+	p := &identInterpolator{programs: make(map[string]*vm.Program)}
+
+	if allow := attrs["ident"]; allow != "" {
+		var err error
+		if p.allow, err = regexp.Compile(allow); err != nil {
+			return nil, fmt.Errorf("bad ident allowlist %s error %w", allow, err)
+		}
+	}
+
+	for _, loc := range refs {
+		ref, e := raw[loc[0]:loc[1]], raw[loc[4]:loc[5]]
+		if _, ok := p.programs[e]; ok {
+			continue
+		}
+
+		program, err := s.compile(e)
+		if err != nil {
+			return nil, &exprError{expr: ref, err: fmt.Errorf("bad identifier %s error %w", ref, err)}
+		}
+
+		p.programs[e] = program
+	}
+
+	return p, nil
+}
+
+// codeIdentRefs returns the submatch indexes of the identifier references out of the string literals
+// and the comments of s, the ones quoted by the backticks like `${table}` are still references.
+func codeIdentRefs(s string) [][]int {
+	var refs [][]int
+
+	for i := 0; i < len(s); {
+		if s[i] != '`' {
+			if end := nonCodeEnd(s, i); end > i {
+				i = end
+				continue
+			}
+		}
+
+		if s[i] == '$' || s[i] == '#' {
+			if loc := identRefRe.FindStringSubmatchIndex(s[i:]); loc != nil {
+				for j := range loc {
+					loc[j] += i
+				}
+
+				refs = append(refs, loc)
+				i = loc[1]
+
+				continue
+			}
+		}
+
+		i++
+	}
+
+	return refs
+}
+
+// interpolate replaces the identifier references in the SQL by the identifiers evaluated with the env.
+func (p *identInterpolator) interpolate(s string, env map[string]interface{}) (string, error) {
+	var err error
+
+	dbType, _ := env[dbTypeKey].(string)
+
+	s = replaceCodeIdentRefs(s, func(loc []int) string {
+		ref := s[loc[0]:loc[1]]
+
+		ident, e := p.eval(s[loc[4]:loc[5]], env)
+		if e != nil {
+			if err == nil {
+				err = &exprError{expr: ref, err: fmt.Errorf("identifier %s error %w", ref, e)}
+			}
+
+			return ref
+		}
+
+		if s[loc[2]] == '#' {
+			return DialectOf(dbType).QuoteIdent(ident)
+		}
+
+		return ident
+	})
+
+	return s, err
+}
+
+// replaceCodeIdentRefs replaces the identifier references of codeIdentRefs in s by repl of their submatch indexes.
+func replaceCodeIdentRefs(s string, repl func(loc []int) string) string {
+	refs := codeIdentRefs(s)
+	if len(refs) == 0 {
+		return s
+	}
+
+	var b strings.Builder
+
+	last := 0
+
+	for _, loc := range refs {
+		b.WriteString(s[last:loc[0]])
+		b.WriteString(repl(loc))
+		last = loc[1]
+	}
+
+	b.WriteString(s[last:])
+
+	return b.String()
+}
+
+// eval evaluates the identifier and checks it against the allowlist.
+func (p *identInterpolator) eval(e string, env map[string]interface{}) (string, error) {
+	output, err := expr.Run(p.programs[e], env)
+	if err != nil {
+		return "", err
+	}
+
+	ident, ok := output.(string)
+	if !ok {
+		return "", fmt.Errorf("%v is not a string", output) // nolint:goerr113
+	}
+
+	if !safeIdentRe.MatchString(ident) {
+		return "", fmt.Errorf("%q is not a safe identifier", ident) // nolint:goerr113
+	}
+
+	if p.allow != nil && !p.allow.MatchString(ident) {
+		return "", fmt.Errorf("%q is not allowed by %s", ident, p.allow) // nolint:goerr113
+	}
+
+	return ident, nil
+}
@@ -0,0 +1,102 @@
+package sqlx
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// includeParamRe matches the {{k}} parameters of the fragments.
+var includeParamRe = regexp.MustCompile(`\{\{\w+}}`) // nolint:gochecknoglobals
+
+// position formats the position like users.sql:12 for the error messages.
+func position(file string, line int) string {
+	if file == "" {
+		return "line " + strconv.Itoa(line)
+	}
+
+	return file + ":" + strconv.Itoa(line)
+}
+
+// resolveIncludes expands the -- include: directives of the items by the fragments.
+func (d *DotSQL) resolveIncludes() error {
+	for name, item := range d.Sqls {
+		resolved, err := d.expandIncludes(item, nil)
+		if err != nil {
+			return err
+		}
+
+		d.Sqls[name] = resolved
+	}
+
+	return nil
+}
+
+// expandIncludes expands the -- include: name k: v directives of the item recursively,
+// the {{k}} in the fragment is replaced by the parameter v, which is distinct from the identifiers like ${table},
+// the stack is the names of fragments being included to detect the cycles.
+// The {{k}} left at the top level is not bound by any include, which is an error.
+func (d *DotSQL) expandIncludes(item DotSQLItem, stack []string) (DotSQLItem, error) {
+	content := make([]string, 0, len(item.Content))
+	lines := make([]int, 0, len(item.Lines))
+
+	for i, l := range item.Content {
+		attrs, name := ParseDotTag(l, "--", "include")
+		if name == "" {
+			content = append(content, l)
+			lines = append(lines, lineOf(item, i))
+
+			continue
+		}
+
+		pos := position(item.File, lineOf(item, i))
+
+		for _, s := range stack {
+			if s == name {
+				return item, fmt.Errorf("dotsql: include cycle %s -> %s at %s", // nolint:goerr113
+					strings.Join(stack, " -> "), name, pos)
+			}
+		}
+
+		fragment, ok := d.Fragments[name]
+		if !ok {
+			return item, fmt.Errorf("dotsql: fragment %s not found at %s", name, pos) // nolint:goerr113
+		}
+
+		included, err := d.expandIncludes(fragment, append(stack, name))
+		if err != nil {
+			return item, err
+		}
+
+		for _, fl := range included.Content {
+			for k, v := range attrs {
+				if k != "include" {
+					fl = strings.ReplaceAll(fl, "{{"+k+"}}", v)
+				}
+			}
+
+			if len(stack) == 0 {
+				if p := includeParamRe.FindString(fl); p != "" {
+					return item, fmt.Errorf("dotsql: include %s parameter %s not bound at %s", // nolint:goerr113
+						name, p, pos)
+				}
+			}
+
+			content = append(content, fl)
+			lines = append(lines, lineOf(item, i))
+		}
+	}
+
+	item.Content, item.Lines = content, lines
+
+	return item, nil
+}
+
+func lineOf(item DotSQLItem, i int) int {
+	if i < len(item.Lines) {
+		return item.Lines[i]
+	}
+
+	return 0
+}
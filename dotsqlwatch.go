@@ -0,0 +1,226 @@
+package sqlx
+
+import (
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DotSQLWatcher watches the .sql files and reloads the DotSQL when they are changed,
+// the reloaded DotSQL is swapped atomically, and the daos created WithSQLWatcher
+// pick up the new statements on their next calls.
+// When the reloading fails, the error is reported by OnError and the old version is kept.
+type DotSQLWatcher struct {
+	// OnError reports the reloading errors, the errors are logged when it is nil.
+	OnError func(err error)
+
+	load func() (*DotSQL, error)
+	stat func() (string, error)
+
+	dotSQL  atomic.Value
+	version uint64
+
+	mu      sync.Mutex
+	sig     string
+	lastErr string
+	stop    chan struct{}
+}
+
+// NewDotSQLFileWatcher creates a DotSQLWatcher of the .sql files.
+func NewDotSQLFileWatcher(sqlFiles ...string) (*DotSQLWatcher, error) {
+	stat := func() (string, error) {
+		infos := make([]fs.FileInfo, len(sqlFiles))
+
+		for i, f := range sqlFiles {
+			info, err := os.Stat(f)
+			if err != nil {
+				return "", err
+			}
+
+			infos[i] = info
+		}
+
+		return signatureOf(sqlFiles, infos), nil
+	}
+
+	return newDotSQLWatcher(func() (*DotSQL, error) { return DotSQLLoadFiles(sqlFiles...) }, stat)
+}
+
+// NewDotSQLFSWatcher creates a DotSQLWatcher of the .sql files of fsys matched by the patterns,
+// like os.DirFS("sql"), the files are globbed again in every checking to find the new ones.
+func NewDotSQLFSWatcher(fsys fs.FS, patterns ...string) (*DotSQLWatcher, error) {
+	stat := func() (string, error) {
+		files, err := globSQLFiles(fsys, patterns)
+		if err != nil {
+			return "", err
+		}
+
+		infos := make([]fs.FileInfo, len(files))
+
+		for i, f := range files {
+			if infos[i], err = fs.Stat(fsys, f); err != nil {
+				return "", err
+			}
+		}
+
+		return signatureOf(files, infos), nil
+	}
+
+	return newDotSQLWatcher(func() (*DotSQL, error) { return DotSQLLoadFS(fsys, patterns...) }, stat)
+}
+
+func newDotSQLWatcher(load func() (*DotSQL, error), stat func() (string, error)) (*DotSQLWatcher, error) {
+	sig, err := stat()
+	if err != nil {
+		return nil, err
+	}
+
+	d, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.parseAll(); err != nil {
+		return nil, err
+	}
+
+	w := &DotSQLWatcher{load: load, stat: stat, sig: sig}
+	w.dotSQL.Store(d)
+
+	return w, nil
+}
+
+// signatureOf returns the signature of the files by their sizes and modification times.
+func signatureOf(files []string, infos []fs.FileInfo) string {
+	var sig strings.Builder
+
+	for i, f := range files {
+		fmt.Fprintf(&sig, "%s %d %d\n", f, infos[i].Size(), infos[i].ModTime().UnixNano())
+	}
+
+	return sig.String()
+}
+
+// parseAll parses all the dynamic SQLs to find the errors in advance.
+func (d *DotSQL) parseAll() error {
+	for name, item := range d.Sqls {
+		if _, err := item.DynamicSQL(); err != nil {
+			return fmt.Errorf("dotsql: parse %s error %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+// DotSQL returns the current DotSQL.
+func (w *DotSQLWatcher) DotSQL() *DotSQL { return w.dotSQL.Load().(*DotSQL) }
+
+// Raw returns the query of the current DotSQL.
+func (w *DotSQLWatcher) Raw(name string) (SQLPart, error) { return w.DotSQL().Raw(name) }
+
+// variant returns the variant of the query tagged by the dbtype in the current DotSQL.
+func (w *DotSQLWatcher) variant(name, dbType string) (SQLPart, bool, error) {
+	return w.DotSQL().variant(name, dbType)
+}
+
+// has tells whether the query of the name is in the current DotSQL.
+func (w *DotSQLWatcher) has(name string) bool {
+	_, err := w.DotSQL().Raw(name)
+	return err == nil
+}
+
+// Version returns the version of the current DotSQL, which is increased on every reloading.
+func (w *DotSQLWatcher) Version() uint64 { return atomic.LoadUint64(&w.version) }
+
+// Check reloads the DotSQL when the files are changed, with the options of the current DotSQL.
+func (w *DotSQLWatcher) Check() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sig, err := w.stat()
+	if err != nil {
+		w.report(err)
+		return
+	}
+
+	if sig == w.sig {
+		return
+	}
+
+	w.sig = sig
+
+	d, err := w.load()
+	if err == nil {
+		err = d.parseAll()
+	}
+
+	if err != nil {
+		w.report(err)
+		return
+	}
+
+	// the options added by Use to the current one are kept, like the custom functions by ExprFunc.
+	d.Use(w.DotSQL().exprOpts...)
+
+	w.lastErr = ""
+	w.dotSQL.Store(d)
+	atomic.AddUint64(&w.version, 1)
+}
+
+// report reports the reloading error, the same error is reported only once in succession.
+func (w *DotSQLWatcher) report(err error) {
+	if err.Error() == w.lastErr {
+		return
+	}
+
+	w.lastErr = err.Error()
+
+	if w.OnError != nil {
+		w.OnError(err)
+	} else {
+		log.Printf("E! reload dotsql error: %v, the old version is kept", err)
+	}
+}
+
+// Start starts checking the files every interval until Stop.
+func (w *DotSQLWatcher) Start(interval time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stop != nil {
+		return
+	}
+
+	stop := make(chan struct{})
+	w.stop = stop
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				w.Check()
+			}
+		}
+	}()
+}
+
+// Stop stops the checking started by Start.
+func (w *DotSQLWatcher) Stop() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.stop != nil {
+		close(w.stop)
+		w.stop = nil
+	}
+}
@@ -5,7 +5,6 @@ import (
 	"github.com/bingoohuang/sqlparser/sqlparser"
 	"github.com/bingoohuang/strcase"
 	"reflect"
-	"strconv"
 	"strings"
 	"unicode"
 )
@@ -61,32 +60,8 @@ func max(a, b int) int {
 	return b
 }
 
-func convertSQLBindMarks(db *sql.DB, s string) string {
-	driverName := LookupDriverName(db.Driver())
-	switch driverName {
-	case "postgres":
-		return replaceQuestionMark4Postgres(s)
-	default:
-		return s
-	}
-}
-
-func replaceQuestionMark4Postgres(s string) string {
-	r := ""
-
-	for seq := 1; ; seq++ {
-		pos := strings.Index(s, "?")
-		if pos < 0 {
-			r += s
-			break
-		}
-
-		r += s[0:pos] + "$" + strconv.Itoa(seq)
-		s = s[pos+1:]
-	}
-
-	return r
-}
+// convertSQLBindMarks rewrites the ? placeholders to the style of the driver of the db, like $1 for postgres.
+func convertSQLBindMarks(db *sql.DB, s string) string { return DialectOfDB(db).Placeholder().Rebind(s) }
 
 func matchesField2Col(structType reflect.Type, field, col string) bool {
 	f, _ := structType.FieldByName(field)
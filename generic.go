@@ -0,0 +1,182 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+
+	"github.com/bingoohuang/gor"
+)
+
+// genericKey is the key of the parsed SQL of the generic helpers,
+// which is parsed once for the query, the func type of the args and results, and the dbtype.
+type genericKey struct {
+	query  string
+	fnType reflect.Type
+	dbType string
+}
+
+// maxGenericParsed is the max number of the parsed SQLs cached for the generic helpers,
+// the queries beyond it, like the ones built by fmt.Sprintf, are parsed on every call.
+const maxGenericParsed = 1024
+
+// nolint:gochecknoglobals
+var (
+	genericParsed = &boundedCache{max: maxGenericParsed} // genericKey -> *SQLParsed
+	int64Type     = reflect.TypeOf(int64(0))
+	anyType       = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// Select queries the rows into the slice of T like the dao func func(args...) ([]T, error),
+// T can be a struct, a map or a single value type like string.
+// The args are bound by sequence like :1 or ?, or by name like :name of the single struct/map arg,
+// and the dynamic SQL like /* if age > 0 */ and age > :age /* end */ is supported.
+func Select[T any](ctx context.Context, db *sql.DB, query string, args ...interface{}) ([]T, error) {
+	values, err := runGeneric(ctx, db, query, 0, genericArgs(args), reflect.TypeOf([]T(nil)))
+	if err != nil {
+		return nil, err
+	}
+
+	return values[0].Interface().([]T), nil
+}
+
+// Get queries the first row into T like Select, sql.ErrNoRows is returned when there are no rows.
+func Get[T any](ctx context.Context, db *sql.DB, query string, args ...interface{}) (T, error) {
+	var t T
+
+	values, err := runGeneric(ctx, db, query, 1, genericArgs(args), reflect.TypeOf([]T(nil)))
+	if err != nil {
+		return t, err
+	}
+
+	if rows := values[0].Interface().([]T); len(rows) > 0 {
+		return rows[0], nil
+	}
+
+	return t, sql.ErrNoRows
+}
+
+// Exec executes the statement like Select, and returns the rows affected and the last insert id.
+func Exec(ctx context.Context, db *sql.DB, query string, args ...interface{}) (sql.Result, error) {
+	return execGeneric(ctx, db, query, genericArgs(args))
+}
+
+// NamedExec executes the statement by the named binds like :name of each of the beans (struct or map)
+// in a transaction, and returns the rows affected and the last insert id of the last bean.
+func NamedExec[T any](ctx context.Context, db *sql.DB, query string, beans ...T) (sql.Result, error) {
+	if beans == nil {
+		beans = []T{}
+	}
+
+	return execGeneric(ctx, db, query, []reflect.Value{reflect.ValueOf(beans)})
+}
+
+// genericResult is the sql.Result of the generic helpers.
+type genericResult struct {
+	rowsAffected, lastInsertID int64
+}
+
+func (r genericResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r genericResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+func execGeneric(ctx context.Context, db *sql.DB, query string, args []reflect.Value) (sql.Result, error) {
+	values, err := runGeneric(ctx, db, query, 0, args, int64Type, int64Type)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(values) < 2 { // no beans
+		return genericResult{}, nil
+	}
+
+	return genericResult{rowsAffected: values[0].Int(), lastInsertID: values[1].Int()}, nil
+}
+
+// genericArgs returns the values of the args, the nil ones are typed by interface{}.
+func genericArgs(args []interface{}) []reflect.Value {
+	values := make([]reflect.Value, len(args))
+
+	for i, arg := range args {
+		if arg == nil {
+			values[i] = reflect.Zero(anyType)
+		} else {
+			values[i] = reflect.ValueOf(arg)
+		}
+	}
+
+	return values
+}
+
+// runGeneric runs the query like the dao func of the types of the args and the outTypes.
+func runGeneric(ctx context.Context, db *sql.DB, query string, maxRows int,
+	args []reflect.Value, outTypes ...reflect.Type) ([]reflect.Value, error) {
+	inTypes := make([]reflect.Type, len(args))
+	for i, arg := range args {
+		inTypes[i] = arg.Type()
+	}
+
+	f := StructField{
+		Name: "sqlx.generic",
+		Type: reflect.FuncOf(inTypes, append(outTypes, gor.ErrType), false),
+		Kind: reflect.Func,
+	}
+
+	parsed, err := genericParse(db, query, f)
+	if err != nil {
+		return nil, err
+	}
+
+	if isQuery := outTypes[0].Kind() == reflect.Slice; isQuery != parsed.IsQuery {
+		if isQuery {
+			return nil, fmt.Errorf("%s is not a query, use Exec instead", query) // nolint:goerr113
+		}
+
+		return nil, fmt.Errorf("%s is a query, use Select or Get instead", query) // nolint:goerr113
+	}
+
+	opt := *parsed.opt
+	opt.DBGetter, opt.QueryMaxRows = MakeDB(db), maxRows
+
+	p := *parsed
+	p.opt, p.ctx = &opt, ctx
+	r := &sqlRun{SQLParsed: &p}
+
+	return r.runFn()(r, len(inTypes), f, outTypes, args)
+}
+
+// genericParse returns the parsed SQL of the query for the func type, which is cached.
+func genericParse(db *sql.DB, query string, f StructField) (*SQLParsed, error) {
+	key := genericKey{query: query, fnType: f.Type, dbType: LookupDriverName(db.Driver())}
+	if v, ok := genericParsed.Load(key); ok {
+		return v.(*SQLParsed), nil
+	}
+
+	opt, err := applyCreateDaoOption(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	opt.Logger = &DaoLoggerNoop{}
+	opt.dbType.Store(key.dbType)
+
+	part, err := (&DotSQLItem{Name: f.Name, Content: []string{query}}).DynamicSQL()
+	if err != nil {
+		return nil, err
+	}
+
+	parsed := &SQLParsed{ID: f.Name, SQL: part, opt: opt}
+	if err := parsed.prepare(); err != nil {
+		return nil, err
+	}
+
+	if err := parsed.prepareSortTags(f.Type); err != nil {
+		return nil, err
+	}
+
+	if err := parsed.compileExpr(f); err != nil {
+		return nil, err
+	}
+
+	return genericParsed.LoadOrStore(key, parsed).(*SQLParsed), nil
+}
@@ -0,0 +1,61 @@
+package sqlx_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenericHelpers(t *testing.T) {
+	that := assert.New(t)
+
+	ctx := context.Background()
+	db := openDB(t)
+
+	_, err := sqlx.Exec(ctx, db, "create table person(id varchar(100), age int)")
+	that.Nil(err)
+
+	r, err := sqlx.NamedExec(ctx, db, "insert into person(id, age) values(:id, :age)",
+		person{"1", 10}, person{"2", 20}, person{"3", 30})
+	that.Nil(err)
+	that.Equal(int64(3), int64Of(r.LastInsertId()))
+
+	r, err = sqlx.Exec(ctx, db, "update person set age = age + 1 where age >= :1", 20)
+	that.Nil(err)
+	that.Equal(int64(2), int64Of(r.RowsAffected()))
+
+	persons, err := sqlx.Select[person](ctx, db, "select id, age from person where age > :1 order by id", 15)
+	that.Nil(err)
+	that.Equal([]person{{"2", 21}, {"3", 31}}, persons)
+
+	persons, err = sqlx.Select[person](ctx, db, "select id, age from person where 1 = 1 "+
+		"/* if id != '' */ and id = :id /* end */ order by id", map[string]interface{}{"id": "1"})
+	that.Nil(err)
+	that.Equal([]person{{"1", 10}}, persons)
+
+	ids, err := sqlx.Select[string](ctx, db, "select id from person order by id desc")
+	that.Nil(err)
+	that.Equal([]string{"3", "2", "1"}, ids)
+
+	count, err := sqlx.Get[int](ctx, db, "select count(*) from person where age < :1", 100)
+	that.Nil(err)
+	that.Equal(3, count)
+
+	p, err := sqlx.Get[person](ctx, db, "select id, age from person where id = :1", "2")
+	that.Nil(err)
+	that.Equal(person{"2", 21}, p)
+
+	_, err = sqlx.Get[person](ctx, db, "select id, age from person where id = :1", "9")
+	that.Equal(sql.ErrNoRows, err)
+
+	_, err = sqlx.Select[person](ctx, db, "delete from person")
+	that.Error(err)
+
+	_, err = sqlx.Exec(ctx, db, "select id from person")
+	that.Error(err)
+}
+
+func int64Of(v int64, _ error) int64 { return v }
@@ -20,8 +20,8 @@ func NewMySQLMore(dbDriver string) *MySQLMore {
 	return &MySQLMore{dbDriver: dbDriver}
 }
 
-// Matches 是否匹配当前实现.
-func (m *MySQLMore) Matches() bool { return m.dbDriver == "mysql" }
+// Matches 是否匹配当前实现, 按驱动的方言判断.
+func (m *MySQLMore) Matches() bool { return DialectOf(m.dbDriver).Name() == "mysql" }
 
 // EnhanceURI 增强URI.
 func (m *MySQLMore) EnhanceURI(dbURI string) string {
@@ -125,7 +125,7 @@ func (m *MySQLDumper) GetTables() ([]string, error) {
 	tables := make([]string, 0)
 
 	// UrlGet table list
-	rows, err := m.Sdb.Query("SHOW TABLES")
+	rows, err := m.Sdb.Query(DialectOfDB(m.Sdb).SchemaSQL().Tables)
 	if err != nil {
 		return tables, err
 	}
@@ -147,13 +147,16 @@ func (m *MySQLDumper) GetTables() ([]string, error) {
 // GetServerVersion get the server version.
 func (m *MySQLDumper) GetServerVersion() (string, error) {
 	var serverVersion sql.NullString
-	if err := m.Sdb.QueryRow("SELECT version()").Scan(&serverVersion); err != nil {
+	if err := m.Sdb.QueryRow(DialectOfDB(m.Sdb).SchemaSQL().Version).Scan(&serverVersion); err != nil {
 		return "", err
 	}
 
 	return serverVersion.String, nil
 }
 
+// quote quotes the table name by the dialect of the db.
+func (m *MySQLDumper) quote(name string) string { return DialectOfDB(m.Sdb).QuoteIdent(name) }
+
 // CreateTable createa a table.
 func (m *MySQLDumper) CreateTable(ct, ds, de *template.Template, writer io.Writer, name string) error {
 	sql, err := m.CreateTableSQL(name)
@@ -161,30 +164,57 @@ func (m *MySQLDumper) CreateTable(ct, ds, de *template.Template, writer io.Write
 		return err
 	}
 
-	if err = ct.Execute(writer, struct{ Name, SQL string }{Name: "`" + name + "`", SQL: sql}); err != nil {
+	if err = ct.Execute(writer, struct{ Name, SQL string }{Name: m.quote(name), SQL: sql}); err != nil {
 		return err
 	}
 
 	return m.CreateTableValues(ds, de, writer, name)
 }
 
-// CreateTableSQL creates a SQL statement to create a table.
+// CreateTableSQL creates a SQL statement to create a table by the dialect of the db.
 func (m *MySQLDumper) CreateTableSQL(name string) (string, error) {
-	var tableReturn sql.NullString
+	query, err := DialectOfDB(m.Sdb).ShowCreateTable(name)
+	if err != nil {
+		return "", err
+	}
 
-	var tableSQL sql.NullString
+	rows, err := m.Sdb.Query(query)
+	if err != nil {
+		return "", err
+	}
 
-	err := m.Sdb.QueryRow("SHOW CREATE TABLE "+name).Scan(&tableReturn, &tableSQL)
+	defer rows.Close()
 
+	columns, err := rows.Columns()
 	if err != nil {
 		return "", err
 	}
 
-	if tableReturn.String != name {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+
+		return "", errors.New("no create table statement for table " + name) // nolint:goerr113
+	}
+
+	// MySQL returns the table name and the statement, SQLite returns the statement only.
+	data := make([]sql.NullString, len(columns))
+	ptrs := make([]interface{}, len(columns))
+
+	for i := range data {
+		ptrs[i] = &data[i]
+	}
+
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", err
+	}
+
+	if len(data) > 1 && data[0].String != name {
 		return "", errors.New("returned table is not the same as requested table") // nolint:goerr113
 	}
 
-	return tableSQL.String, nil
+	return data[len(data)-1].String, nil
 }
 
 // CreateTableValues ...
@@ -224,7 +254,7 @@ func (m *MySQLDumper) CreateTableValues(ds, de *template.Template, writer io.Wri
 		}
 
 		if rowsIndex == 0 {
-			if err = ds.Execute(writer, struct{ Name string }{Name: "`" + name + "`"}); err != nil {
+			if err = ds.Execute(writer, struct{ Name string }{Name: m.quote(name)}); err != nil {
 				return err
 			}
 		}
@@ -249,7 +279,7 @@ func (m *MySQLDumper) CreateTableValues(ds, de *template.Template, writer io.Wri
 	}
 
 	if rowsIndex > 0 {
-		if err = de.Execute(writer, struct{ Name string }{Name: "`" + name + "`"}); err != nil {
+		if err = de.Execute(writer, struct{ Name string }{Name: m.quote(name)}); err != nil {
 			return err
 		}
 	}
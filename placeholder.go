@@ -0,0 +1,273 @@
+package sqlx
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Placeholder is the style of the bind placeholders of the driver.
+type Placeholder int
+
+const (
+	// PlaceholderQuestion is like ? of MySQL and SQLite.
+	PlaceholderQuestion Placeholder = iota
+	// PlaceholderDollar is like $1 of Postgres.
+	PlaceholderDollar
+	// PlaceholderAtP is like @p1 of SQL Server, whose named args are like @name.
+	PlaceholderAtP
+	// PlaceholderColon is like :1 of Oracle, whose named args are like :name.
+	PlaceholderColon
+)
+
+// nolint:gochecknoglobals
+var (
+	placeholdersLock sync.RWMutex
+	placeholders     = map[string]Placeholder{
+		"mysql":     PlaceholderQuestion,
+		"sqlite3":   PlaceholderQuestion,
+		"sqlite":    PlaceholderQuestion,
+		"postgres":  PlaceholderDollar,
+		"pgx":       PlaceholderDollar,
+		"sqlserver": PlaceholderAtP,
+		"mssql":     PlaceholderAtP,
+		"godror":    PlaceholderColon,
+		"oracle":    PlaceholderColon,
+		"oci8":      PlaceholderColon,
+	}
+)
+
+// RegisterPlaceholder registers the placeholder style of the driver name, like the name registered by sql.Register.
+func RegisterPlaceholder(driverName string, p Placeholder) {
+	placeholdersLock.Lock()
+	defer placeholdersLock.Unlock()
+
+	placeholders[driverName] = p
+}
+
+// PlaceholderOf returns the placeholder style of the driver name, PlaceholderQuestion for the unknown ones.
+func PlaceholderOf(driverName string) Placeholder {
+	placeholdersLock.RLock()
+	defer placeholdersLock.RUnlock()
+
+	return placeholders[driverName]
+}
+
+// Mark returns the placeholder of the n-th (1-based) bind var.
+func (p Placeholder) Mark(n int) string {
+	switch p {
+	case PlaceholderDollar:
+		return "$" + strconv.Itoa(n)
+	case PlaceholderAtP:
+		return "@p" + strconv.Itoa(n)
+	case PlaceholderColon:
+		return ":" + strconv.Itoa(n)
+	default:
+		return "?"
+	}
+}
+
+// namedMark returns the placeholder of the named arg like @name, false when the named args are not supported.
+func (p Placeholder) namedMark(name string) (string, bool) {
+	switch p {
+	case PlaceholderAtP:
+		return "@" + name, true
+	case PlaceholderColon:
+		return ":" + name, true
+	default:
+		return "", false
+	}
+}
+
+// Rebind rewrites the ? placeholders of the query to the style,
+// the ? in the literals, the quoted identifiers and the comments are kept as they are.
+func (p Placeholder) Rebind(query string) string {
+	if p == PlaceholderQuestion {
+		return query
+	}
+
+	n := 0
+
+	return replaceQuestionMarks(query, func() string {
+		n++
+		return p.Mark(n)
+	})
+}
+
+// rebindNamed rewrites the ? placeholders of the query to the named args by the names,
+// false when the named args are not supported by the style, or any name is not a valid arg name.
+func (p Placeholder) rebindNamed(query string, names []string) (string, bool) {
+	if _, ok := p.namedMark(""); !ok {
+		return query, false
+	}
+
+	for _, name := range names {
+		if r, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(r) {
+			return query, false
+		}
+	}
+
+	n := 0
+	query = replaceQuestionMarks(query, func() string {
+		mark := "?"
+		if n < len(names) {
+			mark, _ = p.namedMark(names[n])
+		}
+
+		n++
+
+		return mark
+	})
+
+	return query, n == len(names)
+}
+
+// replaceQuestionMarks replaces the ? placeholders out of the literals, the quoted identifiers and the comments.
+func replaceQuestionMarks(s string, replace func() string) string {
+	var out strings.Builder
+
+	for i := 0; i < len(s); {
+		if end := nonCodeEnd(s, i); end > i {
+			out.WriteString(s[i:end])
+			i = end
+
+			continue
+		}
+
+		if s[i] == '?' {
+			out.WriteString(replace())
+		} else {
+			out.WriteByte(s[i])
+		}
+
+		i++
+	}
+
+	return out.String()
+}
+
+// unbind replaces the placeholders of the style out of the literals, the quoted identifiers and the comments
+// by the mark of the 0-based index of the bind var, the named args like @name are indexed by the names.
+func (p Placeholder) unbind(query string, names []string, mark func(index int) string) string {
+	var out strings.Builder
+
+	seq := 0
+
+	for i := 0; i < len(query); {
+		if end := nonCodeEnd(query, i); end > i {
+			out.WriteString(query[i:end])
+			i = end
+
+			continue
+		}
+
+		index, end := p.scanMark(query, i, names)
+		if end == i {
+			out.WriteByte(query[i])
+			i++
+
+			continue
+		}
+
+		if index < 0 { // ?
+			index = seq
+			seq++
+		}
+
+		out.WriteString(mark(index))
+		i = end
+	}
+
+	return out.String()
+}
+
+// scanMark scans the placeholder of the style starting at s[i], and returns its 0-based index (-1 for ?) and end,
+// the end is i when no placeholder starts at i, like the :: of casts, the @@ of variables or the unknown names.
+func (p Placeholder) scanMark(s string, i int, names []string) (int, int) {
+	prefix := p.Mark(1)[0]
+
+	switch {
+	case s[i] != prefix:
+		return 0, i
+	case p == PlaceholderQuestion:
+		return -1, i + 1
+	case i > 0 && (s[i-1] == prefix || isWordChar(s[i-1])),
+		i+1 < len(s) && s[i+1] == prefix:
+		return 0, i
+	}
+
+	j := i + 1
+	if p == PlaceholderAtP && j < len(s) && s[j] == 'p' {
+		j++
+	}
+
+	k := j
+	for k < len(s) && s[k] >= '0' && s[k] <= '9' {
+		k++
+	}
+
+	if n, err := strconv.Atoi(s[j:k]); err == nil && n > 0 {
+		return n - 1, k
+	}
+
+	k = i + 1
+	for k < len(s) && isWordChar(s[k]) {
+		k++
+	}
+
+	for index, name := range names {
+		if name == s[i+1:k] {
+			return index, k
+		}
+	}
+
+	return 0, i
+}
+
+// unnamedArgs returns the values of the sql.Named args, the others are kept as they are.
+func unnamedArgs(args []interface{}) []interface{} {
+	values := make([]interface{}, len(args))
+
+	for i, arg := range args {
+		if named, ok := arg.(sql.NamedArg); ok {
+			values[i] = named.Value
+		} else {
+			values[i] = arg
+		}
+	}
+
+	return values
+}
+
+// uniqueNames returns the names without the duplicate ones, in the order of the args passed by namedArgs.
+func uniqueNames(names []string) []string {
+	unique := make([]string, 0, len(names))
+	seen := make(map[string]bool)
+
+	for _, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			unique = append(unique, name)
+		}
+	}
+
+	return unique
+}
+
+// namedArgs returns the vars as the sql.Named args by the names, the duplicate names are passed once.
+func namedArgs(names []string, vars []interface{}) []interface{} {
+	args := make([]interface{}, 0, len(vars))
+	seen := make(map[string]bool)
+
+	for i, v := range vars {
+		if !seen[names[i]] {
+			seen[names[i]] = true
+			args = append(args, sql.Named(names[i], v))
+		}
+	}
+
+	return args
+}
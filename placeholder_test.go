@@ -0,0 +1,68 @@
+package sqlx_test
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+)
+
+// dollarSQLiteDriver and atSQLiteDriver are sqlite3 drivers of distinct types to be looked up by LookupDriverName,
+// sqlite3 accepts the $1 and @name placeholders too.
+type (
+	dollarSQLiteDriver struct{ sqlite3.SQLiteDriver }
+	atSQLiteDriver     struct{ sqlite3.SQLiteDriver }
+)
+
+// nolint:gochecknoinits
+func init() {
+	sql.Register("sqlite3_dollar", &dollarSQLiteDriver{})
+	sql.Register("sqlite3_at", &atSQLiteDriver{})
+
+	sqlx.RegisterPlaceholder("sqlite3_dollar", sqlx.PlaceholderDollar)
+	sqlx.RegisterPlaceholder("sqlite3_at", sqlx.PlaceholderAtP)
+}
+
+func TestPlaceholderRebind(t *testing.T) {
+	that := assert.New(t)
+
+	query := "select '?', \"?\", ? from t /* ? */ where a = ? -- ?"
+
+	that.Equal(query, sqlx.PlaceholderOf("mysql").Rebind(query))
+	that.Equal(query, sqlx.PlaceholderOf("unknown").Rebind(query))
+	that.Equal("select '?', \"?\", $1 from t /* ? */ where a = $2 -- ?", sqlx.PlaceholderOf("pgx").Rebind(query))
+	that.Equal("select '?', \"?\", @p1 from t /* ? */ where a = @p2 -- ?", sqlx.PlaceholderOf("sqlserver").Rebind(query))
+	that.Equal("select '?', \"?\", :1 from t /* ? */ where a = :2 -- ?", sqlx.PlaceholderOf("godror").Rebind(query))
+}
+
+type placeholderDao struct {
+	CreateTable func()                          `sql:"create table person(id varchar(100), age int)"`
+	Add         func(person)                    `sql:"insert into person(id, age) values(:id, :age)"`
+	Find        func(person) []person           `sql:"select id, age from person where id = :id or age = :age or id = :id order by id"`
+	FindQ       func(id string) (string, error) `sql:"select '?' || id from person where id = :1"`
+}
+
+func TestPlaceholderDrivers(t *testing.T) {
+	for _, driverName := range []string{"sqlite3_dollar", "sqlite3_at"} {
+		that := assert.New(t)
+
+		db, err := sql.Open(driverName, ":memory:")
+		that.Nil(err)
+
+		dao := &placeholderDao{}
+		that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db), sqlx.WithNamedArgs()))
+
+		dao.CreateTable()
+		dao.Add(person{"1", 10})
+		dao.Add(person{"2", 20})
+		dao.Add(person{"3", 30})
+
+		that.Equal([]person{{"1", 10}, {"3", 30}}, dao.Find(person{ID: "1", Age: 30}), driverName)
+
+		id, err := dao.FindQ("2")
+		that.Nil(err)
+		that.Equal("?2", id)
+	}
+}
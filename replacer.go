@@ -0,0 +1,224 @@
+package sqlx
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/bingoohuang/sqlparser/sqlparser"
+)
+
+type queryReplacerKey struct{}
+
+// WithQueryReplacerCtx returns a context which carries the QueryReplacer for the statements called with it
+// as the leading context.Context argument of the dao func, eg. the tenant's table renaming in the request scope.
+func WithQueryReplacerCtx(ctx context.Context, replacer QueryReplacer) context.Context {
+	return context.WithValue(ctx, queryReplacerKey{}, replacer)
+}
+
+// QueryReplacerOf returns the QueryReplacer carried by the ctx, or nil.
+func QueryReplacerOf(ctx context.Context) QueryReplacer {
+	r, _ := ctx.Value(queryReplacerKey{}).(QueryReplacer)
+	return r
+}
+
+// maxReplacedQueries is the max number of the replaced queries cached by the TableReplacer.
+const maxReplacedQueries = 1024
+
+// boundedCache is the cache of the bounded number of entries, the ones beyond it are not cached.
+type boundedCache struct {
+	m    sync.Map
+	size int32
+	max  int32
+}
+
+// Load returns the value of the key cached.
+func (c *boundedCache) Load(key interface{}) (interface{}, bool) { return c.m.Load(key) }
+
+// LoadOrStore caches the value of the key when there is room, and returns the cached one or the value.
+func (c *boundedCache) LoadOrStore(key, value interface{}) interface{} {
+	if atomic.LoadInt32(&c.size) >= c.max || atomic.AddInt32(&c.size, 1) > c.max {
+		return value
+	}
+
+	v, loaded := c.m.LoadOrStore(key, value)
+	if loaded {
+		atomic.AddInt32(&c.size, -1)
+	}
+
+	return v
+}
+
+// TableReplacer is the QueryReplacer which prefixes/renames the table names of the query,
+// the table aliases are kept, and so are the columns qualified by them.
+// The table names are found in the AST of the query, and replaced in the query text,
+// so the query is kept as it is except the table names.
+type TableReplacer struct {
+	prefix string
+	rename map[string]string
+	cache  boundedCache
+}
+
+// NewTableReplacer makes a TableReplacer which prepends the prefix like tenant42_ to the table names,
+// except the ones renamed by the rename map like t_user: tenant42_t_user.
+func NewTableReplacer(prefix string, rename map[string]string) *TableReplacer {
+	r := &TableReplacer{prefix: prefix, rename: make(map[string]string, len(rename))}
+	r.cache.max = maxReplacedQueries
+
+	for k, v := range rename {
+		r.rename[k] = v
+	}
+
+	return r
+}
+
+// ReplacerQuery rewrites the table names of the query.
+func (r *TableReplacer) ReplacerQuery(query string) (string, error) {
+	if v, ok := r.cache.Load(query); ok {
+		return v.(string), nil
+	}
+
+	// the numbered bind marks like $1, @p1 and :1 are not parsable, mark them to parse only.
+	marked := query
+	for _, p := range []Placeholder{PlaceholderDollar, PlaceholderAtP, PlaceholderColon} {
+		marked = p.unbind(marked, nil, bindVarMark)
+	}
+
+	stmt, err := sqlparser.Parse(marked)
+	if err != nil {
+		return "", fmt.Errorf("parse %s to replace tables error %w", query, err)
+	}
+
+	var replaced string
+
+	switch v := stmt.(type) {
+	case *sqlparser.CreateTable:
+		replaced = r.replaceDDL(query, v.Table, v.NewName)
+	case *sqlparser.DDL:
+		replaced = r.replaceDDL(query, v.Table, v.NewName)
+	case *sqlparser.TruncateTable:
+		replaced = r.replaceDDL(query, v.Table)
+	default:
+		if replaced, err = r.replaceDML(query, stmt); err != nil {
+			return "", err
+		}
+	}
+
+	return r.cache.LoadOrStore(query, replaced).(string), nil
+}
+
+// replaceDML replaces the table names of the statement in the query text.
+// The identifiers like the columns, the schemas or the functions named the same as the tables are rejected,
+// because they can not be told from the table names in the query text.
+func (r *TableReplacer) replaceDML(query string, stmt sqlparser.Statement) (string, error) {
+	tables := make(map[string]bool)
+	aliases := make(map[string]bool)
+	others := make(map[string]bool)
+
+	_ = sqlparser.Walk(func(node sqlparser.SQLNode) (bool, error) {
+		switch v := node.(type) {
+		case *sqlparser.AliasedTableExpr:
+			if !v.As.IsEmpty() {
+				aliases[v.As.String()] = true
+			}
+		case sqlparser.TableName:
+			if !v.IsEmpty() {
+				tables[v.Name.String()] = true
+			}
+
+			if !v.Qualifier.IsEmpty() {
+				others[strings.ToLower(v.Qualifier.String())] = true
+			}
+		case sqlparser.ColIdent:
+			others[v.Lowered()] = true
+		}
+
+		return true, nil
+	}, stmt)
+
+	for t := range tables {
+		if aliases[t] {
+			delete(tables, t)
+		} else if others[strings.ToLower(t)] {
+			return "", fmt.Errorf("identifier %s is ambiguous with the table name in %s", t, query) // nolint:goerr113
+		}
+	}
+
+	return r.spliceTables(query, tables), nil
+}
+
+// spliceTables replaces the table names out of the literals and the comments of the query,
+// the names quoted by backticks are replaced in the quotes, and the named binds like :name are kept.
+func (r *TableReplacer) spliceTables(query string, tables map[string]bool) string {
+	var out strings.Builder
+
+	for i := 0; i < len(query); {
+		switch end := nonCodeEnd(query, i); {
+		case query[i] == '`' && end-i > 2 && query[end-1] == '`':
+			if name := query[i+1 : end-1]; tables[name] {
+				out.WriteString("`" + r.replaceTable(name) + "`")
+			} else {
+				out.WriteString(query[i:end])
+			}
+
+			i = end
+		case end > i:
+			out.WriteString(query[i:end])
+			i = end
+		case isWordChar(query[i]):
+			j := i + 1
+			for j < len(query) && isWordChar(query[j]) {
+				j++
+			}
+
+			if word := query[i:j]; tables[word] && (i == 0 || !strings.ContainsRune(":@$", rune(query[i-1]))) {
+				out.WriteString(r.replaceTable(word))
+			} else {
+				out.WriteString(word)
+			}
+
+			i = j
+		default:
+			out.WriteByte(query[i])
+			i++
+		}
+	}
+
+	return out.String()
+}
+
+// replaceDDL replaces the table names in the query text of DDL,
+// because the formatting of DDL by sqlparser is MySQL specific.
+func (r *TableReplacer) replaceDDL(query string, tables ...sqlparser.TableName) string {
+	pos := 0
+
+	for _, t := range tables {
+		if t.IsEmpty() {
+			continue
+		}
+
+		re := regexp.MustCompile(`(?i)\b` + regexp.QuoteMeta(t.Name.String()) + `\b`)
+		loc := re.FindStringIndex(query[pos:])
+
+		if loc == nil {
+			continue
+		}
+
+		renamed := r.replaceTable(t.Name.String())
+		query = query[:pos+loc[0]] + renamed + query[pos+loc[1]:]
+		pos += loc[0] + len(renamed)
+	}
+
+	return query
+}
+
+func (r *TableReplacer) replaceTable(name string) string {
+	if renamed, ok := r.rename[name]; ok {
+		return renamed
+	}
+
+	return r.prefix + name
+}
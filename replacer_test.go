@@ -0,0 +1,85 @@
+package sqlx_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTableReplacer(t *testing.T) {
+	that := assert.New(t)
+
+	r := sqlx.NewTableReplacer("t42_", map[string]string{"t_order": "orders_1"})
+
+	for query, expected := range map[string]string{
+		"select u.id, o.amount from t_user u join t_order o on o.uid = u.id where u.id = ?": "select u.id, o.amount " +
+			"from t42_t_user u join orders_1 o on o.uid = u.id where u.id = ?",
+		"select t_user.name from t_user where id in (select uid from db.t_order)": "select t42_t_user.name " +
+			"from t42_t_user where id in (select uid from db.orders_1)",
+		"insert into t_user(id, name) values(?, ?)": "insert into t42_t_user(id, name) values(?, ?)",
+		"update t_user set name = $1 where id = $2 and name <> 't_user $3'": "update t42_t_user " +
+			"set name = $1 where id = $2 and name <> 't_user $3'",
+		"select * from `t_user` where a = :1 and b = @p2 limit 1 offset 2": "select * from `t42_t_user` " +
+			"where a = :1 and b = @p2 limit 1 offset 2",
+		"select a || b from t_user -- t_user":        "select a || b from t42_t_user -- t_user",
+		"create table t_user(id int, name char(10))": "create table t42_t_user(id int, name char(10))",
+		"drop table if exists t_order":               "drop table if exists orders_1",
+	} {
+		replaced, err := r.ReplacerQuery(query)
+		that.Nil(err)
+		that.Equal(expected, replaced)
+	}
+
+	for _, query := range []string{
+		"select t_user from t_user",
+		"select id from t_user where name = 'a' returning id",
+	} {
+		_, err := r.ReplacerQuery(query)
+		that.Error(err, query)
+	}
+}
+
+type replacerDao struct {
+	CreateTable func()                         `sql:"create table person(id varchar(100), age int)"`
+	Add         func(person)                   `sql:"insert into person(id, age) values(:id, :age)"`
+	ListAll     func() []person                `sql:"select id, age from person order by id"`
+	ListCtx     func(context.Context) []person `sql:"select id, age from person order by id"`
+}
+
+func TestQueryReplacer(t *testing.T) {
+	that := assert.New(t)
+
+	db := openSingleDB(t)
+
+	tenant42 := &replacerDao{}
+	that.Nil(sqlx.CreateDao(tenant42, sqlx.WithDB(db),
+		sqlx.WithQueryReplacer(sqlx.NewTableReplacer("tenant42_", nil))))
+	tenant42.CreateTable()
+	tenant42.Add(person{"a", 1})
+
+	tenant43 := &replacerDao{}
+	that.Nil(sqlx.CreateDao(tenant43, sqlx.WithDB(db),
+		sqlx.WithQueryReplacer(sqlx.NewTableReplacer("tenant43_", nil))))
+	tenant43.CreateTable()
+	tenant43.Add(person{"b", 2})
+
+	that.Equal([]person{{"a", 1}}, tenant42.ListAll())
+	that.Equal([]person{{"b", 2}}, tenant43.ListAll())
+
+	ctx := sqlx.WithQueryReplacerCtx(context.Background(),
+		sqlx.NewTableReplacer("", map[string]string{"tenant42_person": "tenant43_person"}))
+	that.Equal([]person{{"b", 2}}, tenant42.ListCtx(ctx))
+	that.Equal([]person{{"a", 1}}, tenant42.ListAll())
+
+	// one dao serves the tenants by the replacers of the calls.
+	tenants := &replacerDao{}
+	that.Nil(sqlx.CreateDao(tenants, sqlx.WithDB(db)))
+
+	ctx42 := sqlx.WithQueryReplacerCtx(context.Background(), sqlx.NewTableReplacer("tenant42_", nil))
+	ctx43 := sqlx.WithQueryReplacerCtx(context.Background(), sqlx.NewTableReplacer("tenant43_", nil))
+	that.Equal([]person{{"a", 1}}, tenants.ListCtx(ctx42))
+	that.Equal([]person{{"b", 2}}, tenants.ListCtx(ctx43))
+	that.Equal([]person{{"a", 1}}, tenants.ListCtx(ctx42))
+}
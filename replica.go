@@ -0,0 +1,207 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DBRoute tells the details of a statement to be routed.
+type DBRoute struct {
+	// ID is the ID (sqlName) of the statement.
+	ID string
+	// IsQuery tells whether the statement is a query or not.
+	IsQuery bool
+	// Primary tells whether the statement is forced to the primary by the `db:"primary"` tag of dao func.
+	Primary bool
+	// ShardKey is the shard key by the `shardKey:"tenantID"` tag of dao func.
+	ShardKey string
+	// ShardValue is the value of the shard key from the arguments, nil when not found.
+	ShardValue interface{}
+}
+
+// DBRouter is the DBGetter which routes the statements to different databases.
+type DBRouter interface {
+	DBGetter
+	// RouteDB returns the sql.DB for the statement.
+	RouteDB(ctx context.Context, route DBRoute) *sql.DB
+}
+
+type forcePrimaryKey struct{}
+
+// ForcePrimary returns a context which forces the statements to the primary, eg. reads after writes,
+// which is passed as the leading context.Context argument of the dao func.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcePrimaryKey{}, true)
+}
+
+// IsForcePrimary tells whether the ctx forces the statements to the primary or not.
+func IsForcePrimary(ctx context.Context) bool {
+	v, _ := ctx.Value(forcePrimaryKey{}).(bool)
+	return v
+}
+
+// getDB returns the sql.DB routed by the DBRouter, or the DBGetter's sql.DB.
+func (p SQLParsed) getDB() *sql.DB {
+	if router, ok := p.opt.DBGetter.(DBRouter); ok {
+		return router.RouteDB(p.getCtx(), DBRoute{ID: p.ID, IsQuery: p.IsQuery, Primary: p.primary})
+	}
+
+	return p.opt.DBGetter.GetDB()
+}
+
+// ReplicaStrategy defines the strategy to choose a replica.
+type ReplicaStrategy int
+
+const (
+	// RoundRobin chooses the healthy replicas in turn.
+	RoundRobin ReplicaStrategy = iota
+	// LeastLatency chooses the healthy replica with the least ping latency.
+	LeastLatency
+)
+
+type replica struct {
+	db        *sql.DB
+	unhealthy int32
+	latency   int64
+}
+
+// ReplicaSet is the DBRouter for a primary with its replicas.
+// The queries are routed to the healthy replicas, and the others (the writes,
+// including the batch ones in transaction) are routed to the primary.
+// The queries of dao func tagged with `db:"primary"`, or called with the ForcePrimary context,
+// are routed to the primary, and so are the queries when no replicas are healthy.
+// The LeastLatency strategy falls back to RoundRobin until the latencies of all the replicas are checked.
+type ReplicaSet struct {
+	Primary  *sql.DB
+	Strategy ReplicaStrategy
+
+	replicas []*replica
+	next     uint32
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+// NewReplicaSet makes a new ReplicaSet.
+func NewReplicaSet(primary *sql.DB, replicas ...*sql.DB) *ReplicaSet {
+	r := &ReplicaSet{Primary: primary, stop: make(chan struct{})}
+
+	for _, db := range replicas {
+		r.replicas = append(r.replicas, &replica{db: db})
+	}
+
+	return r
+}
+
+// GetDB returns the primary.
+func (r *ReplicaSet) GetDB() *sql.DB { return r.Primary }
+
+// RouteDB returns the sql.DB for the statement.
+func (r *ReplicaSet) RouteDB(ctx context.Context, route DBRoute) *sql.DB {
+	if !route.IsQuery || route.Primary || IsForcePrimary(ctx) {
+		return r.Primary
+	}
+
+	if db := r.pickReplica(); db != nil {
+		return db
+	}
+
+	return r.Primary
+}
+
+func (r *ReplicaSet) pickReplica() *sql.DB {
+	healthy := make([]*replica, 0, len(r.replicas))
+
+	for _, rep := range r.replicas {
+		if atomic.LoadInt32(&rep.unhealthy) == 0 {
+			healthy = append(healthy, rep)
+		}
+	}
+
+	if len(healthy) == 0 {
+		return nil
+	}
+
+	if r.Strategy == LeastLatency {
+		if least := leastLatency(healthy); least != nil {
+			return least.db
+		}
+	}
+
+	n := atomic.AddUint32(&r.next, 1)
+
+	return healthy[(int(n)-1)%len(healthy)].db
+}
+
+// leastLatency returns the replica with the least latency, nil when any latency is unknown (not checked yet).
+func leastLatency(replicas []*replica) *replica {
+	var least *replica
+
+	for _, rep := range replicas {
+		latency := atomic.LoadInt64(&rep.latency)
+		if latency == 0 {
+			return nil
+		}
+
+		if least == nil || latency < atomic.LoadInt64(&least.latency) {
+			least = rep
+		}
+	}
+
+	return least
+}
+
+// CheckHealth pings the replicas to update their health and latencies.
+func (r *ReplicaSet) CheckHealth(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+
+	for _, rep := range r.replicas {
+		wg.Add(1)
+
+		go func(rep *replica) {
+			defer wg.Done()
+
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			start := time.Now()
+
+			if err := rep.db.PingContext(pingCtx); err != nil {
+				atomic.StoreInt32(&rep.unhealthy, 1)
+				return
+			}
+
+			atomic.StoreInt64(&rep.latency, int64(time.Since(start)))
+			atomic.StoreInt32(&rep.unhealthy, 0)
+		}(rep)
+	}
+
+	wg.Wait()
+}
+
+// StartHealthCheck starts to check the health of replicas in the background every interval.
+func (r *ReplicaSet) StartHealthCheck(interval, timeout time.Duration) {
+	r.CheckHealth(context.Background(), timeout)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+				r.CheckHealth(context.Background(), timeout)
+			}
+		}
+	}()
+}
+
+// StopHealthCheck stops the background health checking.
+func (r *ReplicaSet) StopHealthCheck() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
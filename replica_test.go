@@ -0,0 +1,84 @@
+package sqlx_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type replicaDao struct {
+	CreateTable func()                                 `sql:"create table person(id varchar(100), age int)"`
+	Add         func(person)                           `sql:"insert into person(id, age) values(:id, :age)"`
+	ListAll     func() []person                        `sql:"select id, age from person order by id"`
+	ListPrimary func() []person                        `sql:"select id, age from person order by id" db:"primary"`
+	Find        func(string) []person                  `sql:"select id, age from person where id = :1"`
+	ListCtx     func(context.Context) []person         `sql:"select id, age from person order by id"`
+	FindCtx     func(context.Context, string) []person `sql:"select id, age from person where id = :1"`
+}
+
+func openSingleDB(t *testing.T) *sql.DB {
+	db := openDB(t)
+	db.SetMaxOpenConns(1)
+
+	return db
+}
+
+func TestReplicaSet(t *testing.T) {
+	that := assert.New(t)
+
+	primary, replica1, replica2 := openSingleDB(t), openSingleDB(t), openSingleDB(t)
+	rs := sqlx.NewReplicaSet(primary, replica1, replica2)
+
+	for _, db := range []*sql.DB{replica1, replica2} {
+		dao := &replicaDao{}
+		that.Nil(sqlx.CreateDao(dao, sqlx.WithDB(db)))
+		dao.CreateTable()
+		dao.Add(person{"r", 1})
+	}
+
+	dao := &replicaDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDBGetter(rs)))
+	dao.CreateTable()
+	dao.Add(person{"p", 2})
+
+	that.Equal([]person{{"r", 1}}, dao.ListAll())
+	that.Equal([]person{{"r", 1}}, dao.ListAll())
+	that.Equal([]person{{"p", 2}}, dao.ListPrimary())
+
+	// only the call with the ForcePrimary context is routed to the primary.
+	that.Equal([]person{{"p", 2}}, dao.ListCtx(sqlx.ForcePrimary(context.Background())))
+	that.Equal([]person{{"r", 1}}, dao.ListCtx(context.Background()))
+	that.Equal([]person{{"p", 2}}, dao.FindCtx(sqlx.ForcePrimary(context.Background()), "p"))
+	that.Equal([]person{}, dao.FindCtx(nil, "p"))
+	that.Equal([]person{{"r", 1}}, dao.ListAll())
+
+	that.Nil(replica1.Close())
+	that.Nil(replica2.Close())
+	rs.CheckHealth(context.Background(), time.Second)
+	that.Equal([]person{{"p", 2}}, dao.ListAll())
+}
+
+func TestReplicaSetLeastLatency(t *testing.T) {
+	that := assert.New(t)
+
+	primary, replica1, replica2 := openSingleDB(t), openSingleDB(t), openSingleDB(t)
+	rs := sqlx.NewReplicaSet(primary, replica1, replica2)
+	rs.Strategy = sqlx.LeastLatency
+
+	// the latencies are unknown before checking, the replicas are chosen in turn.
+	route := sqlx.DBRoute{IsQuery: true}
+	that.NotEqual(rs.RouteDB(context.Background(), route), rs.RouteDB(context.Background(), route))
+
+	that.Nil(replica2.Close())
+	rs.StartHealthCheck(10*time.Millisecond, time.Second)
+
+	defer rs.StopHealthCheck()
+
+	that.Equal(replica1, rs.RouteDB(context.Background(), route))
+	that.Equal(replica1, rs.RouteDB(context.Background(), route))
+	that.Equal(primary, rs.RouteDB(context.Background(), sqlx.DBRoute{IsQuery: false}))
+}
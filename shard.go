@@ -0,0 +1,624 @@
+package sqlx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bingoohuang/sqlparser/sqlparser"
+)
+
+// DBShard is a database shard.
+type DBShard struct {
+	DB *sql.DB
+	// Replacer rewrites the queries for the shard, eg. renames the tables to the shard's, optional.
+	Replacer QueryReplacer
+}
+
+// ShardRouter is the DBGetter which routes the statements to the shards by the shard key value.
+type ShardRouter interface {
+	DBGetter
+	// RouteShards returns the shards for the statement, more than one shards means to fan out the query.
+	RouteShards(ctx context.Context, route DBRoute) ([]DBShard, error)
+}
+
+// ShardFn maps the shard key value to the index of shards.
+type ShardFn func(shardValue interface{}) (int, error)
+
+// HashShardFn returns the ShardFn which hashes the shard key value to the n shards by FNV-1a,
+// the ShardFn returns an error when n is not positive.
+func HashShardFn(n int) ShardFn {
+	if n <= 0 {
+		return func(interface{}) (int, error) {
+			return 0, fmt.Errorf("hash shard requires positive number of shards, got %d", n) // nolint:goerr113
+		}
+	}
+
+	return func(shardValue interface{}) (int, error) {
+		h := fnv.New32a()
+		_, _ = h.Write([]byte(fmt.Sprint(shardValue)))
+
+		return int(h.Sum32() % uint32(n)), nil
+	}
+}
+
+// ShardSet is the ShardRouter for a set of shards.
+// The statements of dao func tagged with `shardKey:"tenantID"` are routed to the shard
+// by the ShardFn on the value of the named argument tenantID, or like `shardKey:"1"` on the first argument.
+// The queries without the shard key value are fanned out to all the shards when FanOut is true,
+// and the others are rejected.
+type ShardSet struct {
+	Shards  []DBShard
+	ShardFn ShardFn
+	// FanOut tells whether to fan out the queries without the shard key value to all the shards.
+	FanOut bool
+}
+
+// NewShardSet makes a new ShardSet.
+func NewShardSet(fn ShardFn, shards ...DBShard) *ShardSet {
+	return &ShardSet{Shards: shards, ShardFn: fn}
+}
+
+// GetDB returns the first shard's sql.DB, nil when there are no shards.
+func (s *ShardSet) GetDB() *sql.DB {
+	if len(s.Shards) == 0 {
+		return nil
+	}
+
+	return s.Shards[0].DB
+}
+
+// RouteShards returns the shards for the statement.
+func (s *ShardSet) RouteShards(_ context.Context, route DBRoute) ([]DBShard, error) {
+	if len(s.Shards) == 0 {
+		return nil, fmt.Errorf("no shards in the shard set for %s", route.ID) // nolint:goerr113
+	}
+
+	if route.ShardValue == nil {
+		if route.IsQuery && s.FanOut {
+			return s.Shards, nil
+		}
+
+		return nil, fmt.Errorf("shard key %s value is required for %s", route.ShardKey, route.ID) // nolint:goerr113
+	}
+
+	i, err := s.ShardFn(route.ShardValue)
+	if err != nil {
+		return nil, fmt.Errorf("shard %v for %s error %w", route.ShardValue, route.ID, err)
+	}
+
+	if i < 0 || i >= len(s.Shards) {
+		return nil, fmt.Errorf("shard index %d out of range for %s", i, route.ID) // nolint:goerr113
+	}
+
+	return s.Shards[i : i+1], nil
+}
+
+// shardValue finds the shard key value from the args, the shard key is the seq like 1 of the arguments,
+// or the name of the field/map key of the named argument.
+// The pointer value is dereferenced, and the nil pointer one is an error.
+func (p *SQLParsed) shardValue(args []reflect.Value) (interface{}, error) {
+	if p.shardKey == "" || len(args) == 0 || !args[0].IsValid() {
+		return nil, nil
+	}
+
+	var v interface{}
+
+	if seq, err := strconv.Atoi(p.shardKey); err == nil {
+		if seq < 1 || seq > len(args) {
+			return nil, nil
+		}
+
+		v = args[seq-1].Interface()
+	} else {
+		if k := reflect.Indirect(args[0]).Kind(); k != reflect.Struct && k != reflect.Map {
+			return nil, nil
+		}
+
+		v, _ = columnValue(args[0], p.shardKey)
+	}
+
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("shard key %s value is a nil pointer for %s", p.shardKey, p.ID) // nolint:goerr113
+		}
+
+		v = rv.Elem().Interface()
+	}
+
+	return v, nil
+}
+
+// routeDB routes the statement to the shards by the args.
+func (p *SQLParsed) routeDB(args []reflect.Value) ([]DBShard, error) {
+	router, ok := p.opt.DBGetter.(ShardRouter)
+	if !ok {
+		return []DBShard{{DB: p.getDB()}}, nil
+	}
+
+	shardValue, err := p.shardValue(args)
+	if err != nil {
+		return nil, err
+	}
+
+	route := DBRoute{ID: p.ID, IsQuery: p.IsQuery, Primary: p.primary,
+		ShardKey: p.shardKey, ShardValue: shardValue}
+
+	shards, err := router.RouteShards(p.getCtx(), route)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shards) == 0 {
+		return nil, fmt.Errorf("no shards routed for %s", p.ID) // nolint:goerr113
+	}
+
+	return shards, nil
+}
+
+// routeExecDB routes the exec statement to the single shard by the args.
+func (p *SQLParsed) routeExecDB(args []reflect.Value) (DBShard, error) {
+	shards, err := p.routeDB(args)
+	if err != nil {
+		return DBShard{}, err
+	}
+
+	if len(shards) > 1 {
+		return DBShard{}, fmt.Errorf("exec %s cannot be fanned out to shards", p.ID) // nolint:goerr113
+	}
+
+	return shards[0], nil
+}
+
+// execShard is the shard of the batch exec with the items routed to it.
+type execShard struct {
+	DBShard
+	items []reflect.Value
+}
+
+// routeExecItems routes each item of the batch exec to its shard,
+// and groups the items by the shards in the order of their first items.
+func (p *SQLParsed) routeExecItems(items []reflect.Value) ([]*execShard, error) {
+	groups := make([]*execShard, 0, 1)
+
+	for _, item := range items {
+		shard, err := p.routeExecDB([]reflect.Value{item})
+		if err != nil {
+			return nil, err
+		}
+
+		var group *execShard
+
+		for _, g := range groups {
+			if g.DB == shard.DB {
+				group = g
+				break
+			}
+		}
+
+		if group == nil {
+			group = &execShard{DBShard: shard}
+			groups = append(groups, group)
+		}
+
+		group.items = append(group.items, item)
+	}
+
+	return groups, nil
+}
+
+// routeQuery routes the query to the shards by the args, and queries with the vars.
+func (p *SQLParsed) routeQuery(args []reflect.Value, vars []interface{},
+	outTypes []reflect.Type) ([]reflect.Value, error) {
+	shards, err := p.routeDB(args)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(shards) == 1 {
+		p.shardReplacer = shards[0].Replacer
+		return p.query(shards[0].DB, vars, outTypes)
+	}
+
+	return p.fanOutQuery(shards, vars, outTypes)
+}
+
+func (p *SQLParsed) query(db *sql.DB, vars []interface{}, outTypes []reflect.Type) ([]reflect.Value, error) {
+	counterIndex := indexOfTypes(outTypes, CountType)
+
+	rows, counterFn, err := p.doQueryDirectVars(db, vars, counterIndex >= 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.wrapCounter(rows, outTypes, counterIndex, counterFn)
+}
+
+// fanOutQuery queries all the shards, and merges the results by concatenating them,
+// then re-applying the ORDER BY (plain columns only) and the LIMIT of the query.
+// Each shard is queried with the limit of offset+count to keep the merged page correct,
+// and the Count results of shards are summed up.
+func (p *SQLParsed) fanOutQuery(shards []DBShard, vars []interface{},
+	outTypes []reflect.Type) ([]reflect.Value, error) {
+	counterIndex := indexOfTypes(outTypes, CountType)
+	sliceIndex := 0
+
+	if counterIndex == 0 {
+		sliceIndex = 1
+	}
+
+	if outTypes[sliceIndex].Kind() != reflect.Slice {
+		return nil, fmt.Errorf("fan out query %s requires slice result", p.ID) // nolint:goerr113
+	}
+
+	m, err := p.parseFanOut(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := reflect.MakeSlice(outTypes[sliceIndex], 0, 0)
+	total := Count(0)
+
+	for _, shard := range shards {
+		sp := *p
+		sp.runSQL = convertSQLBindMarks(shard.DB, m.query)
+		sp.shardReplacer = shard.Replacer
+		sp.named = false
+
+		values, err := sp.query(shard.DB, m.vars, outTypes)
+		if err != nil {
+			return nil, err
+		}
+
+		merged = reflect.AppendSlice(merged, values[sliceIndex])
+
+		if counterIndex >= 0 {
+			total += values[counterIndex].Interface().(Count)
+		}
+	}
+
+	if merged, err = m.merge(merged); err != nil {
+		return nil, fmt.Errorf("merge fan out query %s error %w", p.ID, err)
+	}
+
+	values := []reflect.Value{merged}
+	if counterIndex >= 0 {
+		values = insert(values, counterIndex, reflect.ValueOf(total))
+	}
+
+	return values, nil
+}
+
+// fanOutMerge defines how to query the shards and merge their results.
+type fanOutMerge struct {
+	query   string
+	vars    []interface{}
+	orderBy sqlparser.OrderBy
+	// orderCols are the result columns of the ORDER BY terms.
+	orderCols []string
+
+	limited          bool
+	offset, rowCount int64
+}
+
+// parseFanOut parses the query for fanning out, the LIMIT offset,count is replaced by LIMIT offset+count.
+// The placeholders of the run SQL are in the style of the dao's db, and the named args are unwrapped
+// to be bound by sequence.
+func (p *SQLParsed) parseFanOut(vars []interface{}) (*fanOutMerge, error) {
+	var names []string
+	if p.named {
+		names = uniqueNames(p.Vars)
+	}
+
+	vars = unnamedArgs(vars)
+	marked := DialectOfDB(p.opt.DBGetter.GetDB()).Placeholder().unbind(p.runSQL, names, bindVarMark)
+
+	stmt, err := sqlparser.Parse(marked)
+	if err != nil {
+		return nil, fmt.Errorf("parse fan out query %s error %w", p.runSQL, err)
+	}
+
+	m := &fanOutMerge{}
+
+	if sel, ok := stmt.(*sqlparser.Select); ok {
+		if m.orderCols, err = fanOutOrderCols(sel); err != nil {
+			return nil, fmt.Errorf("fan out query %s error %w", p.runSQL, err)
+		}
+
+		m.orderBy = sel.OrderBy
+
+		if sel.Limit != nil {
+			if m.offset, err = limitValue(sel.Limit.Offset, vars); err != nil {
+				return nil, err
+			}
+
+			if m.rowCount, err = limitValue(sel.Limit.Rowcount, vars); err != nil {
+				return nil, err
+			}
+
+			m.limited = true
+			sel.Limit = &sqlparser.Limit{
+				Rowcount: sqlparser.NewIntVal([]byte(strconv.FormatInt(m.offset+m.rowCount, 10))),
+			}
+		}
+	}
+
+	query, order := formatBindVars(stmt, p.dialect())
+	m.query, m.vars = query, make([]interface{}, len(order))
+
+	for i, index := range order {
+		m.vars[i] = vars[index]
+	}
+
+	return m, nil
+}
+
+// fanOutOrderCols resolves the ORDER BY terms to the result columns to merge the results,
+// the term is the alias, the selected column or expression (by its alias if any), or the unqualified column name.
+func fanOutOrderCols(sel *sqlparser.Select) ([]string, error) {
+	cols := make([]string, len(sel.OrderBy))
+
+	for i, order := range sel.OrderBy {
+		if cols[i] = selectedCol(sel.SelectExprs, order.Expr); cols[i] == "" {
+			return nil, fmt.Errorf("unsupported order by %s, which should be a selected column or alias", // nolint:goerr113
+				sqlparser.String(order.Expr))
+		}
+	}
+
+	return cols, nil
+}
+
+// selectedCol returns the result column of the ORDER BY term, empty when it is not resolved.
+func selectedCol(exprs sqlparser.SelectExprs, term sqlparser.Expr) string {
+	termCol, isCol := term.(*sqlparser.ColName)
+	unqualified := isCol && termCol.Qualifier.IsEmpty()
+
+	for _, se := range exprs {
+		if ae, ok := se.(*sqlparser.AliasedExpr); ok && unqualified && ae.As.Equal(termCol.Name) {
+			return ae.As.String()
+		}
+	}
+
+	for _, se := range exprs {
+		ae, ok := se.(*sqlparser.AliasedExpr)
+		if !ok || !sameExpr(ae.Expr, term) {
+			continue
+		}
+
+		if !ae.As.IsEmpty() {
+			return ae.As.String()
+		}
+
+		if col, ok := ae.Expr.(*sqlparser.ColName); ok {
+			return col.Name.String()
+		}
+
+		return ""
+	}
+
+	if unqualified {
+		return termCol.Name.String()
+	}
+
+	return ""
+}
+
+// sameExpr tells whether the expressions are the same, the column names are the same when either is unqualified.
+func sameExpr(a, b sqlparser.Expr) bool {
+	ac, ok1 := a.(*sqlparser.ColName)
+	bc, ok2 := b.(*sqlparser.ColName)
+
+	if ok1 && ok2 {
+		return ac.Name.Equal(bc.Name) && (ac.Qualifier.IsEmpty() || bc.Qualifier.IsEmpty() || ac.Qualifier == bc.Qualifier)
+	}
+
+	return sqlparser.String(a) == sqlparser.String(b)
+}
+
+func limitValue(expr sqlparser.Expr, vars []interface{}) (int64, error) {
+	if expr == nil {
+		return 0, nil
+	}
+
+	v, ok := expr.(*sqlparser.SQLVal)
+	if !ok {
+		return 0, fmt.Errorf("unsupported limit %s", sqlparser.String(expr)) // nolint:goerr113
+	}
+
+	switch s := string(v.Val); {
+	case v.Type == sqlparser.IntVal:
+		return strconv.ParseInt(s, 10, 64)
+	case v.Type == sqlparser.ValArg && strings.HasPrefix(s, bindVarMarkPrefix):
+		index, _ := strconv.Atoi(s[len(bindVarMarkPrefix):])
+		if index >= len(vars) {
+			return 0, fmt.Errorf("missing limit var %d", index) // nolint:goerr113
+		}
+
+		rv := reflect.Indirect(reflect.ValueOf(vars[index]))
+
+		switch rv.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return rv.Int(), nil
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return int64(rv.Uint()), nil
+		}
+
+		return 0, fmt.Errorf("unsupported limit var %v", vars[index]) // nolint:goerr113
+	}
+
+	return 0, fmt.Errorf("unsupported limit %s", sqlparser.String(expr)) // nolint:goerr113
+}
+
+// merge sorts the merged slice by the ORDER BY, and slices it by the LIMIT.
+func (m *fanOutMerge) merge(merged reflect.Value) (reflect.Value, error) {
+	if len(m.orderBy) > 0 {
+		keys := make([][]interface{}, merged.Len())
+
+		for i := range keys {
+			keys[i] = make([]interface{}, len(m.orderCols))
+
+			for j, col := range m.orderCols {
+				v, ok := columnValue(merged.Index(i), col)
+				if !ok {
+					return merged, fmt.Errorf("order by column %s is not found in the result", col) // nolint:goerr113
+				}
+
+				keys[i][j] = v
+			}
+		}
+
+		sorter := &resultSorter{rows: merged, keys: keys, orderBy: m.orderBy, swap: reflect.Swapper(merged.Interface())}
+		sort.Stable(sorter)
+	}
+
+	if !m.limited {
+		return merged, nil
+	}
+
+	lo, hi := m.offset, m.offset+m.rowCount
+	if n := int64(merged.Len()); hi > n {
+		hi = n
+	}
+
+	if lo > hi {
+		lo = hi
+	}
+
+	return merged.Slice(int(lo), int(hi)), nil
+}
+
+type resultSorter struct {
+	rows    reflect.Value
+	keys    [][]interface{}
+	orderBy sqlparser.OrderBy
+	swap    func(i, j int)
+}
+
+func (s *resultSorter) Len() int { return s.rows.Len() }
+
+func (s *resultSorter) Swap(i, j int) {
+	s.swap(i, j)
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+func (s *resultSorter) Less(i, j int) bool {
+	for k, order := range s.orderBy {
+		if c := compareValues(s.keys[i][k], s.keys[j][k]); c != 0 {
+			if order.Direction == sqlparser.DescScr {
+				return c > 0
+			}
+
+			return c < 0
+		}
+	}
+
+	return false
+}
+
+// columnValue gets the column value of the row, which is a struct, a map, or a single value.
+func columnValue(row reflect.Value, col string) (interface{}, bool) {
+	row = reflect.Indirect(row)
+
+	switch row.Kind() {
+	case reflect.Struct:
+		t := row.Type()
+
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" && matchesField2Col(t, f.Name, col) {
+				return row.Field(i).Interface(), true
+			}
+		}
+
+		return nil, false
+	case reflect.Map:
+		if row.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+
+		v := row.MapIndex(reflect.ValueOf(col).Convert(row.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+
+		return v.Interface(), true
+	case reflect.Invalid:
+		return nil, false
+	}
+
+	return row.Interface(), true
+}
+
+// compareValues compares the values like the database does, nulls first.
+// nolint:gocyclo
+func compareValues(a, b interface{}) int {
+	if v, ok := a.(driver.Valuer); ok {
+		a, _ = v.Value()
+	}
+
+	if v, ok := b.(driver.Valuer); ok {
+		b, _ = v.Value()
+	}
+
+	va, vb := reflect.Indirect(reflect.ValueOf(a)), reflect.Indirect(reflect.ValueOf(b))
+
+	switch {
+	case !va.IsValid() && !vb.IsValid():
+		return 0
+	case !va.IsValid():
+		return -1
+	case !vb.IsValid():
+		return 1
+	}
+
+	if ta, ok := va.Interface().(time.Time); ok {
+		if tb, ok := vb.Interface().(time.Time); ok {
+			switch {
+			case ta.Before(tb):
+				return -1
+			case ta.After(tb):
+				return 1
+			}
+
+			return 0
+		}
+	}
+
+	switch fa, fb, ok := numberValues(va, vb); {
+	case !ok:
+	case fa < fb:
+		return -1
+	case fa > fb:
+		return 1
+	default:
+		return 0
+	}
+
+	return strings.Compare(fmt.Sprint(va.Interface()), fmt.Sprint(vb.Interface()))
+}
+
+func numberValues(va, vb reflect.Value) (float64, float64, bool) {
+	fa, oka := numberValue(va)
+	fb, okb := numberValue(vb)
+
+	return fa, fb, oka && okb
+}
+
+func numberValue(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,141 @@
+package sqlx_test
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/bingoohuang/sqlx"
+	"github.com/stretchr/testify/assert"
+)
+
+type tenantOrder struct {
+	TenantID string
+	ID       int
+	Amount   int
+}
+
+type shardDao struct {
+	CreateTable  func(string)                                      `sql:"create table t_order(tenant_id varchar(10), id int, amount int)" shardKey:"1"`
+	Add          func(tenantOrder)                                 `sql:"insert into t_order(tenant_id, id, amount) values(:tenantID, :id, :amount)" shardKey:"tenantID"`
+	AddNoKey     func(tenantOrder) error                           `sql:"insert into t_order(tenant_id, id, amount) values(:tenantID, :id, :amount)"`
+	AddBatch     func([]tenantOrder) error                         `sql:"insert into t_order(tenant_id, id, amount) values(:tenantID, :id, :amount)" shardKey:"tenantID"`
+	CountByPtr   func(*string) (int, error)                        `sql:"select count(*) from t_order where tenant_id = :1" shardKey:"1"`
+	ListMarked   func(int) []tenantOrder                           `sql:"select tenant_id, id, amount from t_order where tenant_id <> '?' and id > :1 order by id"`
+	ListByTenant func(string) []tenantOrder                        `sql:"select tenant_id, id, amount from t_order where tenant_id = :1 order by id" shardKey:"1"`
+	TopAmounts   func() []tenantOrder                              `sql:"select tenant_id, id, amount from t_order order by amount desc limit 3"`
+	Page         func(int, int) ([]tenantOrder, sqlx.Count, error) `sql:"select tenant_id, id, amount from t_order order by id limit :1, :2"`
+	LowSwapped   func() []tenantOrder                              `sql:"select o.tenant_id, o.amount as id, o.id as amount from t_order o order by o.amount limit 3"`
+	LowDoubled   func() ([]tenantOrder, error)                     `sql:"select tenant_id, id, amount * 2 from t_order order by amount * 2 limit 3"`
+}
+
+func TestShardSet(t *testing.T) {
+	that := assert.New(t)
+
+	ss := sqlx.NewShardSet(func(v interface{}) (int, error) {
+		switch v {
+		case "a":
+			return 0, nil
+		case "b":
+			return 1, nil
+		}
+
+		return 0, fmt.Errorf("unknown tenant %v", v)
+	}, sqlx.DBShard{DB: openSingleDB(t)}, sqlx.DBShard{
+		DB: openSingleDB(t),
+		Replacer: sqlx.QueryReplacerFn(func(query string) (string, error) {
+			return strings.ReplaceAll(query, "t_order", "t_order_1"), nil
+		}),
+	})
+
+	dao := &shardDao{}
+	that.Nil(sqlx.CreateDao(dao, sqlx.WithDBGetter(ss)))
+
+	dao.CreateTable("a")
+	dao.CreateTable("b")
+
+	for i, amount := range []int{10, 50, 30} {
+		dao.Add(tenantOrder{TenantID: "a", ID: i*2 + 1, Amount: amount})
+		dao.Add(tenantOrder{TenantID: "b", ID: i*2 + 2, Amount: amount + 5})
+	}
+
+	that.NotNil(dao.AddNoKey(tenantOrder{TenantID: "a", ID: 9}))
+
+	that.Equal([]tenantOrder{{"b", 2, 15}, {"b", 4, 55}, {"b", 6, 35}}, dao.ListByTenant("b"))
+
+	that.Nil(dao.TopAmounts())
+
+	ss.FanOut = true
+
+	that.Equal([]tenantOrder{{"b", 4, 55}, {"a", 3, 50}, {"b", 6, 35}}, dao.TopAmounts())
+
+	page, count, err := dao.Page(2, 3)
+	that.Nil(err)
+	that.Equal([]tenantOrder{{"a", 3, 50}, {"b", 4, 55}, {"a", 5, 30}}, page)
+	that.Equal(sqlx.Count(6), count)
+
+	that.Nil(dao.AddBatch([]tenantOrder{{"a", 7, 70}, {"b", 8, 80}, {"a", 9, 90}}))
+	that.Equal([]tenantOrder{{"b", 2, 15}, {"b", 4, 55}, {"b", 6, 35}, {"b", 8, 80}}, dao.ListByTenant("b"))
+	that.Equal([]tenantOrder{{"a", 7, 70}, {"b", 8, 80}, {"a", 9, 90}}, dao.ListMarked(6))
+
+	// the qualified ORDER BY term is merged by its alias in the result.
+	that.Equal([]tenantOrder{{"a", 10, 1}, {"b", 15, 2}, {"a", 30, 5}}, dao.LowSwapped())
+
+	_, err = dao.LowDoubled()
+	that.EqualError(err, "fan out query select tenant_id, id, amount * 2 from t_order order by amount * 2 limit 3 error "+
+		"unsupported order by amount * 2, which should be a selected column or alias")
+
+	tenant := "a"
+	n, err := dao.CountByPtr(&tenant)
+	that.Nil(err)
+	that.Equal(5, n)
+
+	_, err = dao.CountByPtr(nil)
+	that.NotNil(err)
+
+	empty := &shardDao{}
+	that.Nil(sqlx.CreateDao(empty, sqlx.WithDBGetter(sqlx.NewShardSet(sqlx.HashShardFn(1)))))
+	_, err = empty.CountByPtr(&tenant)
+	that.NotNil(err)
+
+	_, err = sqlx.HashShardFn(0)("a")
+	that.EqualError(err, "hash shard requires positive number of shards, got 0")
+}
+
+type shardNamedDao struct {
+	CreateTable func(string)                    `sql:"create table t_order(tenant_id varchar(10), id int, amount int)" shardKey:"1"`
+	Add         func(tenantOrder)               `sql:"insert into t_order(tenant_id, id, amount) values(:tenantID, :id, :amount)" shardKey:"tenantID"`
+	ListAbove   func(tenantOrder) []tenantOrder `sql:"select tenant_id, id, amount from t_order where tenant_id <> '@amount' and amount >= :amount and id >= :amount / 10 order by id limit :id"`
+}
+
+func TestShardSetFanOutPlaceholders(t *testing.T) {
+	for _, driverName := range []string{"sqlite3_dollar", "sqlite3_at"} {
+		that := assert.New(t)
+
+		shards := make([]sqlx.DBShard, 2)
+
+		for i := range shards {
+			db, err := sql.Open(driverName, ":memory:")
+			that.Nil(err)
+
+			db.SetMaxOpenConns(1)
+			shards[i] = sqlx.DBShard{DB: db}
+		}
+
+		ss := sqlx.NewShardSet(sqlx.HashShardFn(2), shards...)
+		ss.FanOut = true
+
+		dao := &shardNamedDao{}
+		that.Nil(sqlx.CreateDao(dao, sqlx.WithDBGetter(ss), sqlx.WithNamedArgs()))
+
+		dao.CreateTable("a")
+		dao.CreateTable("b")
+
+		for i, tenant := range []string{"a", "b", "a", "b"} {
+			dao.Add(tenantOrder{TenantID: tenant, ID: i + 1, Amount: (i + 1) * 10})
+		}
+
+		that.Equal([]tenantOrder{{"b", 2, 20}, {"a", 3, 30}}, dao.ListAbove(tenantOrder{ID: 2, Amount: 20}), driverName)
+	}
+}
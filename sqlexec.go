@@ -39,6 +39,9 @@ func (o ExecOption) reachMaxRows(row int) bool {
 // ExecSQL executes a SQL.
 func ExecSQL(db SQLExec, sqlStr string, option ExecOption) ExecResult {
 	firstKey, isQuerySQL := IsQuerySQL(sqlStr)
+	if sdb, ok := db.(*sql.DB); ok {
+		isQuerySQL = DialectOfDB(sdb).IsQuery(firstKey)
+	}
 
 	if isQuerySQL {
 		return processQuery(db, sqlStr, firstKey, option)
@@ -131,16 +134,21 @@ func execNonQuery(db SQLExec, sqlStr string, firstKey string) ExecResult {
 	}
 }
 
-// IsQuerySQL tests a sql is a query or not.
+// IsQuerySQL tests a sql is a query or not when the database is unknown,
+// which is a query when any registered dialect tells so, see Dialect.IsQuery for the known one.
 func IsQuerySQL(sql string) (string, bool) {
 	key := FirstWord(sql)
 
-	switch strings.ToUpper(key) {
-	case "SELECT", "SHOW", "DESC", "DESCRIBE", "EXPLAIN":
-		return key, true
-	default: // "INSERT", "DELETE", "UPDATE", "SET", "REPLACE":
-		return key, false
+	dialectsLock.RLock()
+	defer dialectsLock.RUnlock()
+
+	for _, d := range dialects {
+		if d.IsQuery(key) {
+			return key, true
+		}
 	}
+
+	return key, false
 }
 
 // FirstWord returns the first word of the SQL statement s.
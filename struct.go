@@ -39,19 +39,19 @@ func (f StructField) GetTagOr(tagName string, defaultValue string) string {
 type StructValue struct {
 	StructSelf reflect.Value
 	NumField   int
+	// FieldTypes are the fields of the struct, copied from the cached ones to be modified freely.
 	FieldTypes []reflect.StructField
 }
 
 // MakeStructValue makes a StructValue by a struct's value.
 func MakeStructValue(structSelf reflect.Value) *StructValue {
-	sv := &StructValue{StructSelf: structSelf, NumField: structSelf.NumField()}
+	fields := structTypeOf(structSelf.Type()).fields
 
-	sv.FieldTypes = make([]reflect.StructField, sv.NumField)
-	for i := 0; i < sv.NumField; i++ {
-		sv.FieldTypes[i] = sv.StructSelf.Type().Field(i)
+	return &StructValue{
+		StructSelf: structSelf,
+		NumField:   structSelf.NumField(),
+		FieldTypes: append(make([]reflect.StructField, 0, len(fields)), fields...),
 	}
-
-	return sv
 }
 
 // FieldIndexByName return's the index of field by its name.
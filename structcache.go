@@ -0,0 +1,122 @@
+package sqlx
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/bingoohuang/strcase"
+)
+
+// nolint:gochecknoglobals
+var (
+	// structTypes caches the mapping metadata of the struct types, which is shared across the daos.
+	structTypes sync.Map // reflect.Type -> *structType
+	// scannerTypes caches whether the types implement sql.Scanner.
+	scannerTypes sync.Map // reflect.Type -> bool
+)
+
+// structType is the mapping metadata of the struct type.
+type structType struct {
+	// fields are the fields of the struct, which are shared and read only.
+	fields []reflect.StructField
+	// names are the bind names of the fields, like the name tag or the lower camel case of the field name.
+	names []string
+	// columns caches the fields matched by the column names, see matchesField2Col.
+	columns sync.Map // string -> columnField
+}
+
+// columnField is the field matched by the column name, ok is false when none is matched.
+type columnField struct {
+	field reflect.StructField
+	ok    bool
+	// value returns the field of the struct value, the top level one is returned without the index path walking.
+	value func(structValue reflect.Value) reflect.Value
+	// set sets the scanned value to the field of the struct value, converted to the field type when required.
+	set func(structValue, val reflect.Value)
+}
+
+// makeColumnField makes the columnField of the matched field with its accessors.
+func makeColumnField(f reflect.StructField) columnField {
+	ft, index := f.Type, f.Index
+	c := columnField{field: f, ok: true, value: func(v reflect.Value) reflect.Value { return v.FieldByIndex(index) }}
+
+	if len(index) == 1 {
+		i := index[0]
+		c.value = func(v reflect.Value) reflect.Value { return v.Field(i) }
+	}
+
+	value := c.value
+	c.set = func(structValue, val reflect.Value) {
+		if val.Type() != ft {
+			val = val.Convert(ft)
+		}
+
+		value(structValue).Set(val)
+	}
+
+	return c
+}
+
+// structTypeOf returns the cached mapping metadata of the struct type.
+func structTypeOf(t reflect.Type) *structType {
+	if v, ok := structTypes.Load(t); ok {
+		return v.(*structType)
+	}
+
+	s := &structType{fields: make([]reflect.StructField, t.NumField()), names: make([]string, t.NumField())}
+
+	for i := range s.fields {
+		f := t.Field(i)
+		s.fields[i] = f
+
+		if tagName := f.Tag.Get("name"); tagName != "" {
+			s.names[i] = tagName
+		} else {
+			s.names[i] = strcase.ToCamelLower(f.Name)
+		}
+	}
+
+	v, _ := structTypes.LoadOrStore(t, s)
+
+	return v.(*structType)
+}
+
+// fieldByColumn returns the field (maybe promoted from the embedded ones) matched by the column name,
+// the matching is cached by the struct type and the column name.
+func fieldByColumn(t reflect.Type, col string) (reflect.StructField, bool) {
+	c := columnFieldOf(t, col)
+	return c.field, c.ok
+}
+
+// columnFieldOf returns the cached field with its accessors matched by the column name.
+func columnFieldOf(t reflect.Type, col string) columnField {
+	s := structTypeOf(t)
+	if v, ok := s.columns.Load(col); ok {
+		return v.(columnField)
+	}
+
+	f, ok := t.FieldByNameFunc(func(field string) bool {
+		return matchesField2Col(t, field, col)
+	})
+
+	c := columnField{}
+	if ok {
+		c = makeColumnField(f)
+	}
+
+	s.columns.Store(col, c)
+
+	return c
+}
+
+// implSQLScanner is the cached ImplSQLScanner.
+func implSQLScanner(t reflect.Type) bool {
+	if v, ok := scannerTypes.Load(t); ok {
+		return v.(bool)
+	}
+
+	impl := ImplSQLScanner(t)
+	scannerTypes.Store(t, impl)
+
+	return impl
+}
@@ -0,0 +1,33 @@
+-- name: MissingEnd
+select * from person
+-- where
+-- if age > 0
+and age = :age
+-- if name != ''
+and name = :name
+-- end
+-- end
+
+-- name: BadExpr
+select * from person
+where 1 = 1 /* if age > */ and age = :age /* end */
+
+-- name: NotBool
+select * from person
+-- where
+-- if age
+and age = :age
+-- end
+-- end
+
+-- name: BadTable
+select * from no_such_table where id = :id
+
+-- name: BadSwitch
+select * from person
+-- where
+and 1 = 1
+-- switch age
+and 2 = 2
+-- end
+-- end
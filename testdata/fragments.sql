@@ -0,0 +1,5 @@
+-- fragment: personCols
+{{alias}}.id, {{alias}}.age
+
+-- fragment: adultOnly
+age >= 18
@@ -0,0 +1,6 @@
+-- name: ListAdults
+select
+-- include: personCols alias: p
+from person p where
+-- include: adultOnly
+order by id;
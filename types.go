@@ -1,6 +1,7 @@
 package sqlx
 
 import (
+	"context"
 	"database/sql"
 	"reflect"
 
@@ -11,6 +12,7 @@ import (
 // nolint:gochecknoglobals
 var (
 	_sqlScannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	_ctxType        = reflect.TypeOf((*context.Context)(nil)).Elem()
 )
 
 // ImplSQLScanner tells t whether it implements sql.Scanner interface.